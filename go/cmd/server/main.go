@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	chimw "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/httprate"
 
+	"pilot-finance/internal/attachments"
 	"pilot-finance/internal/auth"
 	"pilot-finance/internal/config"
 	"pilot-finance/internal/crypto"
@@ -52,6 +54,16 @@ func main() {
 	defer db.Close()
 	log.Println("✓ Base de données connectée")
 
+	// Stockage des pièces jointes chiffrées
+	attachmentsDir := cfg.AttachmentsDir
+	if attachmentsDir == "" {
+		attachmentsDir = filepath.Join(filepath.Dir(dbPath), "attachments")
+	}
+	if err := attachments.Init(attachmentsDir); err != nil {
+		log.Fatalf("Erreur pièces jointes: %v", err)
+	}
+	log.Println("✓ Pièces jointes initialisées")
+
 	// Initialiser les templates
 	if err := templates.Init("templates"); err != nil {
 		log.Fatalf("Erreur templates: %v", err)
@@ -126,11 +138,56 @@ func main() {
 		r.Delete("/accounts/{id}", handlers.DeleteAccount)
 		r.Post("/accounts/{id}/balance", handlers.UpdateBalance)
 		r.Post("/accounts/{id}/move", handlers.MoveAccount)
+		r.Post("/accounts/{id}/valuation", handlers.UpdateValuation)
+		r.Post("/accounts/{id}/fees", handlers.UpdateFees)
+		r.Put("/accounts/{id}/rates", handlers.UpdateRateSchedule)
+		r.Put("/accounts/{id}/pockets", handlers.UpdatePockets)
+		r.Post("/accounts/{id}/term", handlers.UpdateTerm)
+		r.Post("/accounts/{id}/debt", handlers.UpdateDebt)
+		r.Post("/accounts/{id}/trades", handlers.CreateTrade)
+		r.Delete("/accounts/{id}/trades/{tradeId}", handlers.DeleteTrade)
+		r.Post("/accounts/{id}/quotes", handlers.SetQuote)
+		r.Post("/accounts/{id}/cost-basis", handlers.SetCostBasisMethod)
+		r.Post("/accounts/{id}/import", handlers.ImportStatement)
+		r.Get("/accounts/{id}/export", handlers.ExportTransactions)
+		r.Delete("/import-mappings/{id}", handlers.DeleteImportMapping)
+		r.Post("/import/migrate", handlers.MigrateImport)
+
+		r.Post("/accounts/{id}/transactions", handlers.CreateTransaction)
+		r.Put("/transactions/{id}", handlers.UpdateTransaction)
+		r.Delete("/transactions/{id}", handlers.DeleteTransaction)
+		r.Post("/transactions/{id}/cleared", handlers.SetCleared)
+		r.Put("/transactions/{id}/splits", handlers.UpdateSplits)
+		r.Post("/transfers", handlers.CreateTransfer)
+		r.Post("/search/reindex", handlers.ReindexSearch)
+
+		r.Post("/transactions/{id}/attachments", handlers.UploadTransactionAttachment)
+		r.Post("/accounts/{id}/attachments", handlers.UploadAccountAttachment)
+		r.Get("/attachments/{id}", handlers.DownloadAttachment)
+		r.Delete("/attachments/{id}", handlers.DeleteAttachment)
+
+		r.Post("/budgets", handlers.SaveBudget)
+		r.Delete("/budgets/{id}", handlers.DeleteBudget)
+		r.Post("/budget-mode", handlers.SetBudgetMode)
+		r.Post("/envelopes", handlers.SaveEnvelope)
+		r.Delete("/envelopes/{id}", handlers.DeleteEnvelope)
+		r.Post("/envelopes/{id}/assign", handlers.AssignEnvelope)
+		r.Post("/envelopes/move", handlers.MoveEnvelope)
+		r.Post("/accounts/{id}/reconcile", handlers.Reconcile)
+
+		r.Post("/rules", handlers.SaveRule)
+		r.Delete("/rules/{id}", handlers.DeleteRule)
+		r.Post("/rules/apply", handlers.ApplyRules)
 
 		r.Get("/recurring", handlers.RecurringPage)
 		r.Post("/recurring", handlers.CreateRecurring)
 		r.Put("/recurring/{id}", handlers.UpdateRecurring)
 		r.Delete("/recurring/{id}", handlers.DeleteRecurring)
+		r.Post("/recurring/run", handlers.RunRecurring)
+		r.Put("/recurring/{id}/allocations", handlers.UpdateAllocationRules)
+
+		r.Post("/events", handlers.SaveEvent)
+		r.Delete("/events/{id}", handlers.DeleteEvent)
 
 		r.Get("/settings", handlers.SettingsPage)
 		r.Post("/settings/password", handlers.ChangePassword)
@@ -150,6 +207,25 @@ func main() {
 		r.Get("/api/dashboard", handlers.DashboardAPI)
 		r.Get("/api/accounts", handlers.AccountsAPI)
 		r.Get("/api/recurring", handlers.RecurringAPI)
+		r.Get("/api/allocation-rules", handlers.AllocationRulesAPI)
+		r.Get("/api/subscriptions", handlers.SubscriptionsAPI)
+		r.Get("/api/forecast", handlers.ForecastAPI)
+		r.Get("/api/debts/payoff", handlers.DebtPayoffAPI)
+		r.Get("/api/accounts/{id}/gains", handlers.GainsAPI)
+		r.Get("/api/events", handlers.EventsAPI)
+		r.Get("/api/accounts/{id}/transactions", handlers.TransactionsAPI)
+		r.Get("/api/import-mappings", handlers.ImportMappingsAPI)
+		r.Get("/api/rules", handlers.RulesAPI)
+		r.Get("/api/reports/categories", handlers.CategoryReportAPI)
+		r.Get("/api/search", handlers.SearchAPI)
+		r.Get("/api/attachments", handlers.AttachmentsAPI)
+		r.Get("/api/budgets", handlers.BudgetsAPI)
+		r.Get("/api/budgets/report", handlers.BudgetReportAPI)
+		r.Get("/api/budget-mode", handlers.BudgetModeAPI)
+		r.Get("/api/envelopes", handlers.EnvelopesAPI)
+		r.Get("/api/envelopes/report", handlers.EnvelopeReportAPI)
+		r.Get("/api/accounts/{id}/reconcile", handlers.ReconcileAPI)
+		r.Get("/api/accounts/{id}/reconciliations", handlers.ReconciliationsAPI)
 	})
 
 	// Routes admin
@@ -172,6 +248,31 @@ func main() {
 		IdleTimeout:       60 * time.Second,
 	}
 
+	// Exécution automatique des opérations récurrentes (toutes les heures), arrêtée
+	// avant la fermeture de la base
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	if cfg.RecurringAutorun {
+		go func() {
+			defer close(stopped)
+			ticker := time.NewTicker(time.Hour)
+			defer ticker.Stop()
+
+			handlers.RunDueRecurring(time.Now())
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					handlers.RunDueRecurring(time.Now())
+				}
+			}
+		}()
+		log.Println("✓ Opérations récurrentes automatiques")
+	} else {
+		close(stopped)
+	}
+
 	// Graceful shutdown
 	go func() {
 		sigChan := make(chan os.Signal, 1)
@@ -179,6 +280,7 @@ func main() {
 		<-sigChan
 
 		log.Println("Arrêt en cours...")
+		close(done)
 		server.Close()
 	}()
 
@@ -186,6 +288,7 @@ func main() {
 	if err := server.ListenAndServe(); err != http.ErrServerClosed {
 		log.Fatalf("Erreur serveur: %v", err)
 	}
+	<-stopped
 }
 
 // securityHeaders ajoute le CSP (les autres headers sont gérés par Traefik)
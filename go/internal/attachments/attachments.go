@@ -0,0 +1,116 @@
+// Package attachments stocke les pieces jointes chiffrees sur disque.
+package attachments
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"pilot-finance/internal/crypto"
+)
+
+// MaxSize est la taille maximale d'une piece jointe
+const MaxSize = 10 << 20
+
+// Types de fichiers acceptes (detectes sur le contenu, pas sur l'extension)
+var allowedTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/webp":      true,
+}
+
+var (
+	ErrTooLarge        = errors.New("fichier trop volumineux (10 Mo maximum)")
+	ErrUnsupportedType = errors.New("type de fichier non supporte (PDF, JPEG, PNG, WebP)")
+	ErrNotInitialized  = errors.New("stockage des pieces jointes non initialise")
+)
+
+// Noms de stockage generes par Save : 32 caracteres hexadecimaux
+var storageNamePattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+var dir string
+
+// Init prepare le dossier de stockage
+func Init(path string) error {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return fmt.Errorf("création dossier pièces jointes: %w", err)
+	}
+	dir = path
+	return nil
+}
+
+// DetectType retourne le type MIME d'un fichier s'il est accepte
+func DetectType(data []byte) (string, error) {
+	if len(data) > MaxSize {
+		return "", ErrTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	if !allowedTypes[contentType] {
+		return "", ErrUnsupportedType
+	}
+	return contentType, nil
+}
+
+// Save chiffre et ecrit un fichier, et retourne son nom de stockage aleatoire
+func Save(data []byte) (string, error) {
+	if dir == "" {
+		return "", ErrNotInitialized
+	}
+
+	encrypted, err := crypto.EncryptBytes(data)
+	if err != nil {
+		return "", err
+	}
+
+	name := make([]byte, 16)
+	if _, err := rand.Read(name); err != nil {
+		return "", err
+	}
+	storageName := hex.EncodeToString(name)
+
+	if err := os.WriteFile(filepath.Join(dir, storageName), encrypted, 0600); err != nil {
+		return "", err
+	}
+	return storageName, nil
+}
+
+// Load lit et dechiffre un fichier
+func Load(storageName string) ([]byte, error) {
+	path, err := resolve(storageName)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.DecryptBytes(encrypted)
+}
+
+// Remove supprime un fichier (absent = deja supprime)
+func Remove(storageName string) error {
+	path, err := resolve(storageName)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func resolve(storageName string) (string, error) {
+	if dir == "" {
+		return "", ErrNotInitialized
+	}
+	if !storageNamePattern.MatchString(storageName) {
+		return "", errors.New("nom de stockage invalide")
+	}
+	return filepath.Join(dir, storageName), nil
+}
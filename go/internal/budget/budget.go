@@ -0,0 +1,162 @@
+package budget
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"pilot-finance/internal/db"
+	"pilot-finance/internal/ledger"
+)
+
+// Line est le suivi d'un budget de categorie sur un mois
+type Line struct {
+	BudgetID  int64   `json:"budgetId"`
+	Category  string  `json:"category"`
+	Budgeted  float64 `json:"budgeted"`  // Montant du mois
+	Rollover  float64 `json:"rollover"`  // Reste reporte des mois precedents (negatif si depassement)
+	Available float64 `json:"available"` // Budgeted + Rollover
+	Spent     float64 `json:"spent"`     // Depenses nettes des remboursements
+	Remaining float64 `json:"remaining"` // Available - Spent
+	Planned   float64 `json:"planned"`   // Depenses recurrentes prevues sur le mois
+	Upcoming  float64 `json:"upcoming"`  // Part des depenses recurrentes pas encore passee
+	Projected float64 `json:"projected"` // Remaining - Upcoming
+	Overspent bool    `json:"overspent"`
+}
+
+// Report est le budget face au reel pour un mois
+type Report struct {
+	Month          string  `json:"month"` // YYYY-MM
+	Lines          []Line  `json:"lines"`
+	TotalBudgeted  float64 `json:"totalBudgeted"`
+	TotalAvailable float64 `json:"totalAvailable"`
+	TotalSpent     float64 `json:"totalSpent"`
+	TotalRemaining float64 `json:"totalRemaining"`
+	Unbudgeted     float64 `json:"unbudgeted"` // Depenses des categories sans budget
+}
+
+// MonthStart retourne le premier jour du mois de t
+func MonthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// ParseMonth lit un mois au format YYYY-MM
+func ParseMonth(s string) (time.Time, error) {
+	return time.ParseInLocation("2006-01", s, time.Local)
+}
+
+// monthIndex numerote les mois pour les comparer quel que soit le fuseau
+func monthIndex(t time.Time) int {
+	return t.Year()*12 + int(t.Month()) - 1
+}
+
+// spending cumule les depenses nettes par categorie et par mois, hors virements
+type spending map[string]map[int]float64
+
+func spendingByMonth(txs []db.Transaction) spending {
+	s := make(spending)
+	for _, t := range txs {
+		if ledger.IsTransfer(t) {
+			continue
+		}
+		month := monthIndex(t.Date)
+		for _, p := range ledger.Parts(t) {
+			if s[p.Category] == nil {
+				s[p.Category] = make(map[int]float64)
+			}
+			s[p.Category][month] -= p.Amount
+		}
+	}
+	return s
+}
+
+// Compute calcule le budget face au reel pour le mois donne. Les budgets avec report
+// cumulent le reste (ou le depassement) de chaque mois depuis leur mois de depart.
+// now sert a distinguer les depenses recurrentes deja passees de celles a venir.
+func Compute(budgets []db.Budget, txs []db.Transaction, recurrings []db.RecurringOperation, month, now time.Time) Report {
+	current := monthIndex(month)
+	spent := spendingByMonth(txs)
+	planned, upcoming := recurringExpenses(recurrings, current, now)
+
+	report := Report{Month: month.Format("2006-01"), Lines: []Line{}}
+	budgeted := make(map[string]bool)
+
+	for _, b := range budgets {
+		budgeted[b.Category] = true
+		start := monthIndex(b.StartMonth)
+		if current < start {
+			continue
+		}
+
+		var carry float64
+		if b.Rollover {
+			for m := start; m < current; m++ {
+				carry += b.Amount - spent[b.Category][m]
+			}
+		}
+
+		line := Line{
+			BudgetID:  b.ID,
+			Category:  b.Category,
+			Budgeted:  b.Amount,
+			Rollover:  round(carry),
+			Available: round(b.Amount + carry),
+			Spent:     round(spent[b.Category][current]),
+			Planned:   round(planned[b.Category]),
+			Upcoming:  round(upcoming[b.Category]),
+		}
+		line.Remaining = round(line.Available - line.Spent)
+		line.Projected = round(line.Remaining - line.Upcoming)
+		line.Overspent = line.Remaining < 0
+
+		report.Lines = append(report.Lines, line)
+		report.TotalBudgeted += line.Budgeted
+		report.TotalAvailable += line.Available
+		report.TotalSpent += line.Spent
+		report.TotalRemaining += line.Remaining
+	}
+
+	for category, byMonth := range spent {
+		if !budgeted[category] && byMonth[current] > 0 {
+			report.Unbudgeted += byMonth[current]
+		}
+	}
+
+	sort.Slice(report.Lines, func(i, j int) bool {
+		return report.Lines[i].Category < report.Lines[j].Category
+	})
+	report.TotalBudgeted = round(report.TotalBudgeted)
+	report.TotalAvailable = round(report.TotalAvailable)
+	report.TotalSpent = round(report.TotalSpent)
+	report.TotalRemaining = round(report.TotalRemaining)
+	report.Unbudgeted = round(report.Unbudgeted)
+	return report
+}
+
+// recurringExpenses totalise par categorie les depenses recurrentes actives du mois,
+// et celles dont le jour d'execution n'est pas encore atteint
+func recurringExpenses(recurrings []db.RecurringOperation, month int, now time.Time) (planned, upcoming map[string]float64) {
+	planned = make(map[string]float64)
+	upcoming = make(map[string]float64)
+	current := monthIndex(now)
+
+	for _, rec := range recurrings {
+		if !rec.IsActive || rec.ToAccountID != nil || rec.Amount >= 0 || rec.Category == nil {
+			continue
+		}
+		amount := -rec.Amount
+		planned[*rec.Category] += amount
+
+		switch {
+		case month > current:
+			upcoming[*rec.Category] += amount
+		case month == current && rec.DayOfMonth > now.Day():
+			upcoming[*rec.Category] += amount
+		}
+	}
+	return planned, upcoming
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,106 @@
+package budget
+
+import (
+	"testing"
+	"time"
+
+	"pilot-finance/internal/db"
+)
+
+func TestComputeRolloverAndRecurring(t *testing.T) {
+	month := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 0, 0, 0, 0, time.UTC) }
+	food, home := "Courses", "Maison"
+	linked := int64(9)
+	budgets := []db.Budget{
+		{ID: 1, Category: food, Amount: 300, Rollover: true, StartMonth: month(1, 1)},
+		{ID: 2, Category: home, Amount: 100, StartMonth: month(1, 1)},
+	}
+	txs := []db.Transaction{
+		{Amount: -250, Date: month(1, 10), Category: &food},
+		{Amount: -380, Date: month(2, 3), Category: &food},
+		{Amount: -120, Date: month(3, 2), Splits: []db.TransactionSplit{
+			{Category: &food, Amount: -90},
+			{Category: &home, Amount: -30},
+		}},
+		{Amount: 20, Date: month(3, 4), Category: &food}, // remboursement
+		{Amount: -500, Date: month(3, 5), Category: &home, LinkedID: &linked},
+		{Amount: -40, Date: month(3, 6)},
+	}
+	recurrings := []db.RecurringOperation{
+		{Amount: -60, DayOfMonth: 20, IsActive: true, Category: &home},
+		{Amount: -10, DayOfMonth: 1, IsActive: true, Category: &home},
+	}
+
+	report := Compute(budgets, txs, recurrings, month(3, 1), month(3, 15))
+	if len(report.Lines) != 2 {
+		t.Fatalf("lines = %+v", report.Lines)
+	}
+
+	f := report.Lines[0]
+	if f.Category != food || f.Rollover != -30 || f.Available != 270 || f.Spent != 70 || f.Remaining != 200 {
+		t.Errorf("food = %+v", f)
+	}
+
+	h := report.Lines[1]
+	if h.Rollover != 0 || h.Spent != 30 || h.Planned != 70 || h.Upcoming != 60 || h.Projected != 10 {
+		t.Errorf("home = %+v", h)
+	}
+	if report.Unbudgeted != 40 || report.TotalSpent != 100 {
+		t.Errorf("report = %+v", report)
+	}
+
+	if before := Compute(budgets, txs, recurrings, month(1, 1).AddDate(0, -1, 0), month(3, 15)); len(before.Lines) != 0 {
+		t.Errorf("lines before start = %+v", before.Lines)
+	}
+}
+
+func TestComputeEnvelopes(t *testing.T) {
+	day := func(m time.Month, d int) time.Time { return time.Date(2024, m, d, 0, 0, 0, 0, time.UTC) }
+	food, fun, sport, salary := "Courses", "Loisirs", "Sport", "Salaire"
+	envelopes := []db.Envelope{
+		{ID: 1, Name: "Courses", Category: food, CreatedAt: day(1, 1)},
+		{ID: 2, Name: "Loisirs", Category: fun, CreatedAt: day(1, 1)},
+		{ID: 3, Name: "Sport", Category: sport, CreatedAt: day(2, 1)},
+	}
+	allocations := []db.EnvelopeAllocation{
+		{EnvelopeID: 1, Month: day(1, 1), Amount: 400},
+		{EnvelopeID: 2, Month: day(1, 1), Amount: 100},
+		{EnvelopeID: 1, Month: day(2, 1), Amount: 300},
+	}
+	moves := []db.EnvelopeMove{
+		{FromEnvelopeID: 2, ToEnvelopeID: 1, Amount: 50, Month: day(2, 1)},
+	}
+	txs := []db.Transaction{
+		{Amount: 2000, Date: day(1, 2), Category: &salary},
+		{Amount: -350, Date: day(1, 12), Category: &food},
+		{Amount: -60, Date: day(1, 20), Category: &sport},
+		{Amount: 2000, Date: day(2, 2), Category: &salary},
+		{Amount: -420, Date: day(2, 10), Category: &food},
+		{Amount: -30, Date: day(2, 11)},
+		{Amount: -25, Date: day(2, 15), Category: &sport},
+		{Amount: 1000, Date: day(3, 1), Category: &salary},
+	}
+
+	report := ComputeEnvelopes(envelopes, allocations, moves, txs, day(2, 1))
+	if report.Income != 2000 || report.Unbudgeted != 30 || report.Assigned != 300 {
+		t.Errorf("report = %+v", report)
+	}
+	// 4000 de revenus - 800 affectes - 30 de depenses hors enveloppe - 60 de sport
+	// depenses avant la creation de son enveloppe
+	if report.ToBeBudgeted != 3110 {
+		t.Errorf("toBeBudgeted = %v", report.ToBeBudgeted)
+	}
+
+	f := report.Envelopes[0]
+	if f.Carried != 50 || f.Assigned != 300 || f.Moved != 50 || f.Activity != -420 || f.Available != -20 || !f.Overspent {
+		t.Errorf("food = %+v", f)
+	}
+	l := report.Envelopes[1]
+	if l.Carried != 100 || l.Moved != -50 || l.Available != 50 {
+		t.Errorf("fun = %+v", l)
+	}
+	s := report.Envelopes[2]
+	if s.Carried != 0 || s.Activity != -25 || s.Available != -25 {
+		t.Errorf("sport = %+v", s)
+	}
+}
@@ -0,0 +1,162 @@
+package budget
+
+import (
+	"time"
+
+	"pilot-finance/internal/db"
+	"pilot-finance/internal/ledger"
+)
+
+// Modes de budget
+const (
+	ModeCategory = "CATEGORY"
+	ModeEnvelope = "ENVELOPE"
+)
+
+// EnvelopeLine est l'etat d'une enveloppe sur un mois
+type EnvelopeLine struct {
+	EnvelopeID int64    `json:"envelopeId"`
+	Name       string   `json:"name"`
+	Category   string   `json:"category"`
+	Goal       *float64 `json:"goal"`
+	Carried    float64  `json:"carried"`   // Disponible a la fin du mois precedent
+	Assigned   float64  `json:"assigned"`  // Affecte ce mois depuis les revenus
+	Moved      float64  `json:"moved"`     // Solde des transferts avec les autres enveloppes
+	Activity   float64  `json:"activity"`  // Depenses (negatif) et remboursements du mois
+	Available  float64  `json:"available"` // Carried + Assigned + Moved + Activity
+	Overspent  bool     `json:"overspent"`
+}
+
+// EnvelopeReport est le budget base zero d'un mois
+type EnvelopeReport struct {
+	Month      string  `json:"month"` // YYYY-MM
+	Income     float64 `json:"income"`
+	Unbudgeted float64 `json:"unbudgeted"` // Depenses hors enveloppe du mois (positif)
+	Assigned   float64 `json:"assigned"`
+	// ToBeBudgeted est l'argent recu mais pas encore affecte, cumule depuis le
+	// mois de la premiere enveloppe
+	ToBeBudgeted float64        `json:"toBeBudgeted"`
+	Envelopes    []EnvelopeLine `json:"envelopes"`
+}
+
+// envelopeFlows cumule les mouvements d'une enveloppe : ceux des mois precedents
+// sont reportes, ceux du mois sont ventiles par nature
+type envelopeFlows struct {
+	carried, assigned, moved, activity float64
+}
+
+func (f *envelopeFlows) add(m, current int, v float64, field *float64) {
+	switch {
+	case m < current:
+		f.carried += v
+	case m == current:
+		*field += v
+	}
+}
+
+// ComputeEnvelopes calcule le budget base zero du mois donne. Les revenus et les
+// depenses hors enveloppe alimentent ou reduisent l'argent a affecter ; les depenses
+// d'une categorie d'enveloppe la consomment depuis le mois de creation de
+// l'enveloppe, et comptent avant comme des depenses hors enveloppe. Un depassement
+// reste a la charge de l'enveloppe les mois suivants.
+func ComputeEnvelopes(envelopes []db.Envelope, allocations []db.EnvelopeAllocation, moves []db.EnvelopeMove, txs []db.Transaction, month time.Time) EnvelopeReport {
+	current := monthIndex(month)
+	report := EnvelopeReport{Month: month.Format("2006-01"), Envelopes: []EnvelopeLine{}}
+	if len(envelopes) == 0 {
+		return report
+	}
+
+	byCategory := make(map[string]int64)
+	startByID := make(map[int64]int)
+	poolStart := current
+	for _, e := range envelopes {
+		byCategory[e.Category] = e.ID
+		startByID[e.ID] = monthIndex(e.CreatedAt)
+		if startByID[e.ID] < poolStart {
+			poolStart = startByID[e.ID]
+		}
+	}
+
+	byEnvelope := make(map[int64]*envelopeFlows)
+	for _, e := range envelopes {
+		byEnvelope[e.ID] = &envelopeFlows{}
+	}
+
+	var pool float64
+	for _, a := range allocations {
+		m := monthIndex(a.Month)
+		if m > current {
+			continue
+		}
+		f, ok := byEnvelope[a.EnvelopeID]
+		if !ok {
+			continue
+		}
+		pool -= a.Amount
+		if m == current {
+			report.Assigned += a.Amount
+		}
+		f.add(m, current, a.Amount, &f.assigned)
+	}
+
+	for _, mv := range moves {
+		m := monthIndex(mv.Month)
+		if from, ok := byEnvelope[mv.FromEnvelopeID]; ok {
+			from.add(m, current, -mv.Amount, &from.moved)
+		}
+		if to, ok := byEnvelope[mv.ToEnvelopeID]; ok {
+			to.add(m, current, mv.Amount, &to.moved)
+		}
+	}
+
+	for _, t := range txs {
+		m := monthIndex(t.Date)
+		if m > current || ledger.IsTransfer(t) {
+			continue
+		}
+		for _, p := range ledger.Parts(t) {
+			if id, ok := byCategory[p.Category]; ok && m >= startByID[id] {
+				f := byEnvelope[id]
+				f.add(m, current, p.Amount, &f.activity)
+				continue
+			}
+			if m < poolStart {
+				continue
+			}
+			pool += p.Amount
+			if m == current {
+				if p.Amount > 0 {
+					report.Income += p.Amount
+				} else {
+					report.Unbudgeted -= p.Amount
+				}
+			}
+		}
+	}
+
+	for _, e := range envelopes {
+		if startByID[e.ID] > current {
+			continue
+		}
+		f := byEnvelope[e.ID]
+		line := EnvelopeLine{
+			EnvelopeID: e.ID,
+			Name:       e.Name,
+			Category:   e.Category,
+			Goal:       e.Goal,
+			Carried:    round(f.carried),
+			Assigned:   round(f.assigned),
+			Moved:      round(f.moved),
+			Activity:   round(f.activity),
+			Available:  round(f.carried + f.assigned + f.moved + f.activity),
+		}
+		line.Overspent = line.Available < 0
+		report.Envelopes = append(report.Envelopes, line)
+	}
+
+	report.Income = round(report.Income)
+	report.Unbudgeted = round(report.Unbudgeted)
+	report.Assigned = round(report.Assigned)
+	report.ToBeBudgeted = round(pool)
+	return report
+}
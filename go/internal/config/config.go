@@ -15,14 +15,18 @@ type Config struct {
 	// Base de données
 	DatabaseURL string
 
+	// Pièces jointes (par défaut à côté de la base)
+	AttachmentsDir string
+
 	// Sécurité
 	AuthSecret     string
 	EncryptionKey  string
 	BlindIndexKey  string
 
 	// Fonctionnalités
-	AllowRegister bool
-	EnableMail    bool
+	AllowRegister    bool
+	EnableMail       bool
+	RecurringAutorun bool // Exécution automatique des opérations récurrentes échues
 
 	// SMTP (optionnel)
 	SMTPHost string
@@ -38,11 +42,13 @@ func Load() (*Config, error) {
 		Host:          getEnv("HOST", "localhost"),
 		Port:          getEnv("PORT", "3000"),
 		DatabaseURL:   getEnv("DATABASE_URL", "file:./data/pilot.db"),
+		AttachmentsDir: os.Getenv("ATTACHMENTS_DIR"),
 		AuthSecret:    os.Getenv("AUTH_SECRET"),
 		EncryptionKey: os.Getenv("ENCRYPTION_KEY"),
 		BlindIndexKey: os.Getenv("BLIND_INDEX_KEY"),
 		AllowRegister: getEnv("ALLOW_REGISTER", "false") == "true",
 		EnableMail:    getEnv("ENABLE_MAIL", "false") == "true",
+		RecurringAutorun: getEnv("RECURRING_AUTORUN", "false") == "true",
 		SMTPHost:      os.Getenv("SMTP_HOST"),
 		SMTPPort:      getEnv("SMTP_PORT", "587"),
 		SMTPUser:      os.Getenv("SMTP_USER"),
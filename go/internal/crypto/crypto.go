@@ -134,6 +134,51 @@ func Decrypt(encrypted string) (string, error) {
 	return string(plaintext), nil
 }
 
+// EncryptBytes chiffre des données binaires (pièces jointes) avec AES-256-GCM
+// Format de sortie: IV (12 bytes) || CIPHERTEXT || AUTH_TAG (16 bytes)
+func EncryptBytes(data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	iv := make([]byte, ivLength, ivLength+len(data)+authTagLength)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(iv, iv, data, nil), nil
+}
+
+// DecryptBytes déchiffre des données produites par EncryptBytes
+func DecryptBytes(data []byte) ([]byte, error) {
+	if len(data) < ivLength+authTagLength {
+		return nil, ErrDecryption
+	}
+
+	block, err := aes.NewCipher(encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := gcm.Open(nil, data[:ivLength], data[ivLength:], nil)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+
+	return plaintext, nil
+}
+
 // ComputeBlindIndex calcule un index aveugle HMAC-SHA256
 // Compatible avec Node.js: createHmac('sha256', key).update(input).digest('hex')
 func ComputeBlindIndex(input string) string {
@@ -130,3 +130,28 @@ func TestDecryptNonEncrypted(t *testing.T) {
 		t.Errorf("Decrypt(%q) = %q, want %q", plaintext, result, plaintext)
 	}
 }
+
+func TestEncryptDecryptBytes(t *testing.T) {
+	if err := Init(testEncryptionKey, testBlindIndexKey); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("%PDF-1.4 \x00\x01\x02 binaire")
+	encrypted, err := EncryptBytes(data)
+	if err != nil {
+		t.Fatalf("EncryptBytes failed: %v", err)
+	}
+	if string(encrypted[ivLength:]) == string(data) {
+		t.Error("données non chiffrées")
+	}
+
+	decrypted, err := DecryptBytes(encrypted)
+	if err != nil || string(decrypted) != string(data) {
+		t.Errorf("DecryptBytes = %q, %v", decrypted, err)
+	}
+
+	encrypted[len(encrypted)-1] ^= 0xff
+	if _, err := DecryptBytes(encrypted); err != ErrDecryption {
+		t.Errorf("altération non détectée: %v", err)
+	}
+}
@@ -84,21 +84,22 @@ func SwapAccountPositions(id1, id2, userID int64) error {
 	return tx.Commit()
 }
 
-// CreateRecurring cree une operation recurrente
-func CreateRecurring(userID, accountID int64, toAccountID *int64, description string, amount float64, dayOfMonth int) error {
+// CreateRecurring cree une operation recurrente ; elle ne s'execute qu'a partir de sa
+// date de creation
+func CreateRecurring(userID, accountID int64, toAccountID *int64, description string, amount float64, dayOfMonth int, category *string) error {
 	_, err := DB.Exec(`
-		INSERT INTO recurring_operations (user_id, account_id, to_account_id, description, amount, day_of_month, is_active)
-		VALUES (?, ?, ?, ?, ?, ?, 1)
-	`, userID, accountID, toAccountID, description, amount, dayOfMonth)
+		INSERT INTO recurring_operations (user_id, account_id, to_account_id, description, amount, day_of_month, created_at, is_active, category)
+		VALUES (?, ?, ?, ?, ?, ?, ?, 1, ?)
+	`, userID, accountID, toAccountID, description, amount, dayOfMonth, time.Now().Unix(), category)
 	return err
 }
 
 // UpdateRecurring met a jour une operation recurrente
-func UpdateRecurring(id, userID int64, description string, amount float64, dayOfMonth int, toAccountID *int64) error {
+func UpdateRecurring(id, userID int64, description string, amount float64, dayOfMonth int, toAccountID *int64, category *string) error {
 	_, err := DB.Exec(`
-		UPDATE recurring_operations SET description = ?, amount = ?, day_of_month = ?, to_account_id = ?
+		UPDATE recurring_operations SET description = ?, amount = ?, day_of_month = ?, to_account_id = ?, category = ?
 		WHERE id = ? AND user_id = ?
-	`, description, amount, dayOfMonth, toAccountID, id, userID)
+	`, description, amount, dayOfMonth, toAccountID, category, id, userID)
 	return err
 }
 
@@ -107,3 +108,55 @@ func DeleteRecurring(id, userID int64) error {
 	_, err := DB.Exec(`DELETE FROM recurring_operations WHERE id = ? AND user_id = ?`, id, userID)
 	return err
 }
+
+// UpdateAccountValuation met a jour le type de compte et la regle de valorisation d'un bien
+func UpdateAccountValuation(id, userID int64, kind string, purchasePrice float64, purchaseDate *time.Time, rule string, rate float64, depreciationYears int) error {
+	var purchaseDateUnix *int64
+	if purchaseDate != nil {
+		u := purchaseDate.Unix()
+		purchaseDateUnix = &u
+	}
+	_, err := DB.Exec(`
+		UPDATE accounts SET kind = ?, purchase_price = ?, purchase_date = ?, valuation_rule = ?,
+		valuation_rate = ?, depreciation_years = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`, kind, purchasePrice, purchaseDateUnix, rule, rate, depreciationYears, time.Now().Unix(), id, userID)
+	return err
+}
+
+// UpdateAccountFees met a jour les frais de gestion d'un compte
+func UpdateAccountFees(id, userID int64, annualRate, depositRate, fixedYearly float64) error {
+	_, err := DB.Exec(`
+		UPDATE accounts SET fee_annual_rate = ?, fee_deposit_rate = ?, fee_fixed_yearly = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`, annualRate, depositRate, fixedYearly, time.Now().Unix(), id, userID)
+	return err
+}
+
+// UpdateAccountDebt met a jour le type de compte et les parametres d'une dette
+func UpdateAccountDebt(id, userID int64, kind string, rate, minPayment float64) error {
+	_, err := DB.Exec(`
+		UPDATE accounts SET kind = ?, debt_rate = ?, debt_min_payment = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`, kind, rate, minPayment, time.Now().Unix(), id, userID)
+	return err
+}
+
+// UpdateAccountTerm met a jour les parametres d'un compte a terme
+func UpdateAccountTerm(id, userID int64, kind string, rate float64, startDate, maturityDate *time.Time, rollover string, targetAccountID *int64) error {
+	var startUnix, maturityUnix *int64
+	if startDate != nil {
+		u := startDate.Unix()
+		startUnix = &u
+	}
+	if maturityDate != nil {
+		u := maturityDate.Unix()
+		maturityUnix = &u
+	}
+	_, err := DB.Exec(`
+		UPDATE accounts SET kind = ?, term_rate = ?, term_start_date = ?, term_maturity_date = ?,
+		term_rollover = ?, term_target_account_id = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`, kind, rate, startUnix, maturityUnix, rollover, targetAccountID, time.Now().Unix(), id, userID)
+	return err
+}
@@ -0,0 +1,64 @@
+package db
+
+import "time"
+
+// GetAllocationRulesByUserID récupère les regles de repartition des revenus d'un utilisateur
+func GetAllocationRulesByUserID(userID int64) ([]AllocationRule, error) {
+	rows, err := DB.Query(`
+		SELECT id, user_id, recurring_id, to_account_id, percent, position, created_at
+		FROM allocation_rules WHERE user_id = ? ORDER BY recurring_id ASC, position ASC, id ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []AllocationRule
+	for rows.Next() {
+		var r AllocationRule
+		var createdAt int64
+		if err := rows.Scan(&r.ID, &r.UserID, &r.RecurringID, &r.ToAccountID, &r.Percent, &r.Position, &createdAt); err != nil {
+			return nil, err
+		}
+		r.CreatedAt = time.Unix(createdAt, 0)
+		rules = append(rules, r)
+	}
+
+	return rules, rows.Err()
+}
+
+// AllocationRulesByRecurring groupe les regles de repartition par operation de revenu
+func AllocationRulesByRecurring(rules []AllocationRule) map[int64][]AllocationRule {
+	byRecurring := make(map[int64][]AllocationRule)
+	for _, r := range rules {
+		byRecurring[r.RecurringID] = append(byRecurring[r.RecurringID], r)
+	}
+	return byRecurring
+}
+
+// ReplaceAllocationRules remplace la repartition d'une operation de revenu
+func ReplaceAllocationRules(recurringID, userID int64, rules []AllocationRule) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM allocation_rules WHERE recurring_id = ? AND user_id = ?`, recurringID, userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	for i, r := range rules {
+		_, err = tx.Exec(`
+			INSERT INTO allocation_rules (user_id, recurring_id, to_account_id, percent, position, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, userID, recurringID, r.ToAccountID, r.Percent, i, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+const attachmentColumns = `id, user_id, transaction_id, account_id, filename, content_type, size, storage_name, created_at`
+
+func scanAttachment(row rowScanner) (Attachment, error) {
+	var a Attachment
+	var transactionID, accountID sql.NullInt64
+	var createdAt int64
+	err := row.Scan(&a.ID, &a.UserID, &transactionID, &accountID, &a.Filename,
+		&a.ContentType, &a.Size, &a.StorageName, &createdAt)
+	if err != nil {
+		return a, err
+	}
+	if transactionID.Valid {
+		a.TransactionID = &transactionID.Int64
+	}
+	if accountID.Valid {
+		a.AccountID = &accountID.Int64
+	}
+	a.CreatedAt = time.Unix(createdAt, 0)
+	return a, nil
+}
+
+func queryAttachments(query string, args ...interface{}) ([]Attachment, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Attachment
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, a)
+	}
+
+	return list, rows.Err()
+}
+
+// CreateAttachment enregistre une piece jointe et retourne son ID
+func CreateAttachment(a Attachment) (int64, error) {
+	res, err := DB.Exec(`
+		INSERT INTO attachments (user_id, transaction_id, account_id, filename, content_type, size, storage_name, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, a.UserID, a.TransactionID, a.AccountID, a.Filename, a.ContentType, a.Size, a.StorageName, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetAttachmentByID récupère une piece jointe de l'utilisateur (nil si absente)
+func GetAttachmentByID(id, userID int64) (*Attachment, error) {
+	a, err := scanAttachment(DB.QueryRow(`
+		SELECT `+attachmentColumns+` FROM attachments WHERE id = ? AND user_id = ?
+	`, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// GetAttachmentsByTransaction récupère les pieces jointes d'une transaction
+func GetAttachmentsByTransaction(transactionID, userID int64) ([]Attachment, error) {
+	return queryAttachments(`
+		SELECT `+attachmentColumns+` FROM attachments
+		WHERE transaction_id = ? AND user_id = ? ORDER BY id
+	`, transactionID, userID)
+}
+
+// GetAttachmentsByAccount récupère les pieces jointes rattachees directement a un compte
+func GetAttachmentsByAccount(accountID, userID int64) ([]Attachment, error) {
+	return queryAttachments(`
+		SELECT `+attachmentColumns+` FROM attachments
+		WHERE account_id = ? AND user_id = ? ORDER BY id
+	`, accountID, userID)
+}
+
+// GetAttachmentsUnderAccount récupère les pieces jointes d'un compte et de ses transactions
+func GetAttachmentsUnderAccount(accountID, userID int64) ([]Attachment, error) {
+	return queryAttachments(`
+		SELECT `+attachmentColumns+` FROM attachments
+		WHERE user_id = ? AND (account_id = ? OR transaction_id IN (
+			SELECT id FROM transactions WHERE account_id = ? AND user_id = ?
+		))
+	`, userID, accountID, accountID, userID)
+}
+
+// GetAttachmentsUnderTransaction récupère les pieces jointes d'une transaction et
+// de l'autre ecriture s'il s'agit d'un virement
+func GetAttachmentsUnderTransaction(transactionID, userID int64) ([]Attachment, error) {
+	return queryAttachments(`
+		SELECT `+attachmentColumns+` FROM attachments
+		WHERE user_id = ? AND transaction_id IN (
+			SELECT id FROM transactions WHERE user_id = ? AND (id = ? OR linked_id = ?)
+		)
+	`, userID, userID, transactionID, transactionID)
+}
+
+// GetAttachmentsByUserID récupère toutes les pieces jointes d'un utilisateur
+func GetAttachmentsByUserID(userID int64) ([]Attachment, error) {
+	return queryAttachments(`
+		SELECT `+attachmentColumns+` FROM attachments WHERE user_id = ?
+	`, userID)
+}
+
+// DeleteAttachment supprime une piece jointe
+func DeleteAttachment(id, userID int64) error {
+	_, err := DB.Exec(`DELETE FROM attachments WHERE id = ? AND user_id = ?`, id, userID)
+	return err
+}
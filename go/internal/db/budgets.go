@@ -0,0 +1,46 @@
+package db
+
+import "time"
+
+// GetBudgetsByUserID récupère les budgets de categorie d'un utilisateur
+func GetBudgetsByUserID(userID int64) ([]Budget, error) {
+	rows, err := DB.Query(`
+		SELECT id, user_id, category, amount, rollover, start_month, created_at
+		FROM budgets WHERE user_id = ? ORDER BY category ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var budgets []Budget
+	for rows.Next() {
+		var b Budget
+		var startMonth, createdAt int64
+		if err := rows.Scan(&b.ID, &b.UserID, &b.Category, &b.Amount, &b.Rollover, &startMonth, &createdAt); err != nil {
+			return nil, err
+		}
+		b.StartMonth = time.Unix(startMonth, 0)
+		b.CreatedAt = time.Unix(createdAt, 0)
+		budgets = append(budgets, b)
+	}
+
+	return budgets, rows.Err()
+}
+
+// SaveBudget cree ou met a jour le budget d'une categorie
+func SaveBudget(b Budget) error {
+	_, err := DB.Exec(`
+		INSERT INTO budgets (user_id, category, amount, rollover, start_month, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, category) DO UPDATE SET
+			amount = excluded.amount, rollover = excluded.rollover, start_month = excluded.start_month
+	`, b.UserID, b.Category, b.Amount, b.Rollover, b.StartMonth.Unix(), time.Now().Unix())
+	return err
+}
+
+// DeleteBudget supprime un budget de categorie
+func DeleteBudget(id, userID int64) error {
+	_, err := DB.Exec(`DELETE FROM budgets WHERE id = ? AND user_id = ?`, id, userID)
+	return err
+}
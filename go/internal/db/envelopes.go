@@ -0,0 +1,145 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// GetBudgetMode retourne le mode de budget de l'utilisateur (CATEGORY ou ENVELOPE)
+func GetBudgetMode(userID int64) (string, error) {
+	var mode string
+	err := DB.QueryRow(`SELECT budget_mode FROM users WHERE id = ?`, userID).Scan(&mode)
+	return mode, err
+}
+
+// SetBudgetMode change le mode de budget de l'utilisateur
+func SetBudgetMode(userID int64, mode string) error {
+	_, err := DB.Exec(`UPDATE users SET budget_mode = ? WHERE id = ?`, mode, userID)
+	return err
+}
+
+// GetEnvelopesByUserID récupère les enveloppes d'un utilisateur
+func GetEnvelopesByUserID(userID int64) ([]Envelope, error) {
+	rows, err := DB.Query(`
+		SELECT id, user_id, name, category, goal, position, created_at
+		FROM envelopes WHERE user_id = ? ORDER BY position ASC, id ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var envelopes []Envelope
+	for rows.Next() {
+		var e Envelope
+		var goal sql.NullFloat64
+		var createdAt int64
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Name, &e.Category, &goal, &e.Position, &createdAt); err != nil {
+			return nil, err
+		}
+		if goal.Valid {
+			e.Goal = &goal.Float64
+		}
+		e.CreatedAt = time.Unix(createdAt, 0)
+		envelopes = append(envelopes, e)
+	}
+
+	return envelopes, rows.Err()
+}
+
+// CreateEnvelope cree une enveloppe
+func CreateEnvelope(e Envelope) (int64, error) {
+	res, err := DB.Exec(`
+		INSERT INTO envelopes (user_id, name, category, goal, position, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, e.UserID, e.Name, e.Category, e.Goal, e.Position, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// UpdateEnvelope met a jour le nom, la categorie et l'objectif d'une enveloppe
+func UpdateEnvelope(e Envelope) error {
+	_, err := DB.Exec(`
+		UPDATE envelopes SET name = ?, category = ?, goal = ?
+		WHERE id = ? AND user_id = ?
+	`, e.Name, e.Category, e.Goal, e.ID, e.UserID)
+	return err
+}
+
+// DeleteEnvelope supprime une enveloppe avec ses affectations et transferts
+func DeleteEnvelope(id, userID int64) error {
+	_, err := DB.Exec(`DELETE FROM envelopes WHERE id = ? AND user_id = ?`, id, userID)
+	return err
+}
+
+// GetEnvelopeAllocationsByUserID récupère toutes les affectations mensuelles
+func GetEnvelopeAllocationsByUserID(userID int64) ([]EnvelopeAllocation, error) {
+	rows, err := DB.Query(`
+		SELECT id, user_id, envelope_id, month, amount
+		FROM envelope_allocations WHERE user_id = ? ORDER BY month ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var allocations []EnvelopeAllocation
+	for rows.Next() {
+		var a EnvelopeAllocation
+		var month int64
+		if err := rows.Scan(&a.ID, &a.UserID, &a.EnvelopeID, &month, &a.Amount); err != nil {
+			return nil, err
+		}
+		a.Month = time.Unix(month, 0)
+		allocations = append(allocations, a)
+	}
+
+	return allocations, rows.Err()
+}
+
+// SetEnvelopeAllocation fixe le montant affecte a une enveloppe pour un mois
+func SetEnvelopeAllocation(userID, envelopeID int64, month time.Time, amount float64) error {
+	_, err := DB.Exec(`
+		INSERT INTO envelope_allocations (user_id, envelope_id, month, amount)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(envelope_id, month) DO UPDATE SET amount = excluded.amount
+	`, userID, envelopeID, month.Unix(), amount)
+	return err
+}
+
+// GetEnvelopeMovesByUserID récupère les transferts entre enveloppes
+func GetEnvelopeMovesByUserID(userID int64) ([]EnvelopeMove, error) {
+	rows, err := DB.Query(`
+		SELECT id, user_id, from_envelope_id, to_envelope_id, amount, month, created_at
+		FROM envelope_moves WHERE user_id = ? ORDER BY month ASC, id ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var moves []EnvelopeMove
+	for rows.Next() {
+		var m EnvelopeMove
+		var month, createdAt int64
+		if err := rows.Scan(&m.ID, &m.UserID, &m.FromEnvelopeID, &m.ToEnvelopeID, &m.Amount, &month, &createdAt); err != nil {
+			return nil, err
+		}
+		m.Month = time.Unix(month, 0)
+		m.CreatedAt = time.Unix(createdAt, 0)
+		moves = append(moves, m)
+	}
+
+	return moves, rows.Err()
+}
+
+// CreateEnvelopeMove enregistre un transfert entre deux enveloppes
+func CreateEnvelopeMove(m EnvelopeMove) error {
+	_, err := DB.Exec(`
+		INSERT INTO envelope_moves (user_id, from_envelope_id, to_envelope_id, amount, month, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, m.UserID, m.FromEnvelopeID, m.ToEnvelopeID, m.Amount, m.Month.Unix(), time.Now().Unix())
+	return err
+}
@@ -0,0 +1,52 @@
+package db
+
+import "time"
+
+// CreatePlannedEvent cree un evenement ponctuel prevu
+func CreatePlannedEvent(userID, accountID int64, description string, amount float64, date time.Time) error {
+	_, err := DB.Exec(`
+		INSERT INTO planned_events (user_id, account_id, description, amount, date)
+		VALUES (?, ?, ?, ?, ?)
+	`, userID, accountID, description, amount, date.Unix())
+	return err
+}
+
+// UpdatePlannedEvent met a jour un evenement ponctuel
+func UpdatePlannedEvent(id, userID, accountID int64, description string, amount float64, date time.Time) error {
+	_, err := DB.Exec(`
+		UPDATE planned_events SET account_id = ?, description = ?, amount = ?, date = ?
+		WHERE id = ? AND user_id = ?
+	`, accountID, description, amount, date.Unix(), id, userID)
+	return err
+}
+
+// DeletePlannedEvent supprime un evenement ponctuel
+func DeletePlannedEvent(id, userID int64) error {
+	_, err := DB.Exec(`DELETE FROM planned_events WHERE id = ? AND user_id = ?`, id, userID)
+	return err
+}
+
+// GetPlannedEventsByUserID récupère les evenements ponctuels d'un utilisateur par date
+func GetPlannedEventsByUserID(userID int64) ([]PlannedEvent, error) {
+	rows, err := DB.Query(`
+		SELECT id, user_id, account_id, amount, description, date
+		FROM planned_events WHERE user_id = ? ORDER BY date ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []PlannedEvent
+	for rows.Next() {
+		var e PlannedEvent
+		var date int64
+		if err := rows.Scan(&e.ID, &e.UserID, &e.AccountID, &e.Amount, &e.Description, &date); err != nil {
+			return nil, err
+		}
+		e.Date = time.Unix(date, 0)
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
@@ -48,18 +48,146 @@ type Account struct {
 	LastYieldDate    *time.Time `json:"last_yield_date"`
 	ReinvestmentRate int        `json:"reinvestment_rate"` // 0-100
 	TargetAccountID  *int64     `json:"target_account_id"`
+	CostBasisMethod  string     `json:"cost_basis_method"` // PRU ou FIFO
+	Kind             string     `json:"kind"`              // STANDARD, ASSET, TERM_DEPOSIT, LIABILITY
+	// Valorisation des biens (Kind ASSET)
+	PurchasePrice     float64    `json:"purchase_price"`
+	PurchaseDate      *time.Time `json:"purchase_date"`
+	ValuationRule     string     `json:"valuation_rule"`     // ANNUAL_RATE ou LINEAR
+	ValuationRate     float64    `json:"valuation_rate"`     // % annuel, negatif pour une decote
+	DepreciationYears int        `json:"depreciation_years"` // Duree d'amortissement LINEAR
+	// Frais de gestion
+	FeeAnnualRate  float64 `json:"fee_annual_rate"`  // % annuel sur l'encours
+	FeeDepositRate float64 `json:"fee_deposit_rate"` // % sur les versements
+	FeeFixedYearly float64 `json:"fee_fixed_yearly"` // Montant fixe annuel
+	// Compte a terme (Kind TERM_DEPOSIT)
+	TermRate            float64    `json:"term_rate"` // % annuel, interets verses a l'echeance
+	TermStartDate       *time.Time `json:"term_start_date"`
+	TermMaturityDate    *time.Time `json:"term_maturity_date"`
+	TermRollover        string     `json:"term_rollover"` // ROLLOVER ou TRANSFER
+	TermTargetAccountID *int64     `json:"term_target_account_id"`
+	// Dette (Kind LIABILITY) : le solde negatif est le capital restant du
+	DebtRate       float64 `json:"debt_rate"`        // % annuel
+	DebtMinPayment float64 `json:"debt_min_payment"` // Mensualite minimale
+	// Bareme de taux (remplace YieldMin/YieldMax quand il est renseigne)
+	RateSchedule []RateSegment `json:"rate_schedule"`
+	// Poches virtuelles qui répartissent le solde
+	Pockets []Pocket `json:"pockets"`
+}
+
+// Pocket représente une poche virtuelle (fonds d'urgence, vacances) dans un compte réel
+type Pocket struct {
+	ID        int64    `json:"id"`
+	AccountID int64    `json:"account_id"`
+	Name      string   `json:"name"` // Chiffré en BDD
+	Amount    float64  `json:"amount"`
+	Goal      *float64 `json:"goal"`
+	Color     string   `json:"color"`
+}
+
+// RateSegment représente un palier de taux d'un compte, en vigueur à partir d'une date.
+// Les segments de même date forment des tranches de solde (taux marginal au-dessus de TierMin).
+type RateSegment struct {
+	ID            int64     `json:"id"`
+	AccountID     int64     `json:"account_id"`
+	EffectiveDate time.Time `json:"effective_date"`
+	TierMin       float64   `json:"tier_min"`
+	Rate          float64   `json:"rate"`
 }
 
 // Transaction représente une transaction
 type Transaction struct {
-	ID          int64     `json:"id"`
-	UserID      int64     `json:"user_id"`
-	AccountID   int64     `json:"account_id"`
-	Amount      float64   `json:"amount"`
-	Description string    `json:"description"` // Chiffré en BDD
-	Category    *string   `json:"category"`
-	Date        time.Time `json:"date"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID               int64              `json:"id"`
+	UserID           int64              `json:"user_id"`
+	AccountID        int64              `json:"account_id"`
+	Amount           float64            `json:"amount"`
+	Description      string             `json:"description"` // Chiffré en BDD
+	Category         *string            `json:"category"`
+	Payee            *string            `json:"payee"` // Chiffré en BDD
+	Tags             *string            `json:"tags"`  // Chiffré en BDD, separees par des virgules
+	Date             time.Time          `json:"date"`
+	Cleared          bool               `json:"cleared"`           // Pointee sur un releve
+	ReconciliationID *int64             `json:"reconciliation_id"` // Point de controle qui l'a validee
+	LinkedID         *int64             `json:"linked_id"`         // Autre ecriture d'un virement entre comptes
+	ExternalRef      *string            `json:"-"`                 // Index aveugle de l'identifiant banque (FITID)
+	Fingerprint      *string            `json:"-"`                 // Empreinte HMAC pour la detection des doublons
+	CreatedAt        time.Time          `json:"created_at"`
+	SearchTokens     []string           `json:"-"`                // Mots-cles en index aveugle, ecrits avec la transaction
+	Splits           []TransactionSplit `json:"splits,omitempty"` // Ventilation par categorie
+}
+
+// TransactionSplit représente une part d'une transaction ventilée sur plusieurs catégories
+type TransactionSplit struct {
+	ID            int64   `json:"id"`
+	TransactionID int64   `json:"transaction_id"`
+	Category      *string `json:"category"`
+	Amount        float64 `json:"amount"`
+	Memo          *string `json:"memo"` // Chiffré en BDD
+}
+
+// Trade représente un achat ou une vente de titres sur un compte-titres
+type Trade struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	AccountID int64     `json:"account_id"`
+	Symbol    string    `json:"symbol"` // Chiffré en BDD
+	Side      string    `json:"side"`   // BUY ou SELL
+	Quantity  float64   `json:"quantity"`
+	Price     float64   `json:"price"`
+	Fees      float64   `json:"fees"`
+	Date      time.Time `json:"date"`
+}
+
+// Quote représente le dernier cours connu d'un titre
+type Quote struct {
+	ID        int64     `json:"id"`
+	AccountID int64     `json:"account_id"`
+	Symbol    string    `json:"symbol"` // Chiffré en BDD
+	Price     float64   `json:"price"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Attachment représente une pièce jointe chiffrée stockée sur disque
+type Attachment struct {
+	ID            int64     `json:"id"`
+	UserID        int64     `json:"userId"`
+	TransactionID *int64    `json:"transactionId"`
+	AccountID     *int64    `json:"accountId"`
+	Filename      string    `json:"filename"` // Chiffré en BDD
+	ContentType   string    `json:"contentType"`
+	Size          int64     `json:"size"`
+	StorageName   string    `json:"-"` // Nom du fichier chiffré dans le dossier des pièces jointes
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// Reconciliation représente un point de contrôle de rapprochement bancaire
+type Reconciliation struct {
+	ID               int64     `json:"id"`
+	UserID           int64     `json:"userId"`
+	AccountID        int64     `json:"accountId"`
+	StatementDate    time.Time `json:"statementDate"`
+	StatementBalance float64   `json:"statementBalance"` // Solde du releve a cette date
+	OpeningBalance   float64   `json:"openingBalance"`   // Solde du point de controle precedent
+	CreatedAt        time.Time `json:"createdAt"`
+}
+
+// CategorizationRule représente une règle de catégorisation automatique
+type CategorizationRule struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"userId"`
+	Definition string    `json:"definition"` // Chiffré en BDD (JSON de rules.Rule)
+	Position   int       `json:"position"`
+	IsActive   bool      `json:"isActive"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// ImportMapping représente une correspondance de colonnes CSV enregistrée pour une banque
+type ImportMapping struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"user_id"`
+	Name      string    `json:"name"`   // Chiffré en BDD
+	Config    string    `json:"config"` // JSON de importer.CSVMapping
+	CreatedAt time.Time `json:"created_at"`
 }
 
 // RecurringOperation représente une opération récurrente
@@ -73,6 +201,73 @@ type RecurringOperation struct {
 	DayOfMonth  int        `json:"dayOfMonth"`
 	LastRunDate *time.Time `json:"lastRunDate"`
 	IsActive    bool       `json:"isActive"`
+	Category    *string    `json:"category"`  // Catégorie budgétaire (dépenses)
+	CreatedAt   *time.Time `json:"createdAt"` // Absente pour les opérations antérieures
+}
+
+// AllocationRule répartit automatiquement un revenu récurrent vers un compte
+// (« se payer en premier ») : un pourcentage du montant y est viré à chaque exécution
+type AllocationRule struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"userId"`
+	RecurringID int64     `json:"recurringId"` // Opération de revenu répartie
+	ToAccountID int64     `json:"toAccountId"`
+	Percent     float64   `json:"percent"` // % du revenu
+	Position    int       `json:"position"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// PlannedEvent représente une opération ponctuelle prévue (achat, héritage, frais de scolarité)
+type PlannedEvent struct {
+	ID          int64     `json:"id"`
+	UserID      int64     `json:"userId"`
+	AccountID   int64     `json:"accountId"`
+	Amount      float64   `json:"amount"`
+	Description string    `json:"description"` // Chiffré en BDD
+	Date        time.Time `json:"date"`
+}
+
+// Budget représente l'enveloppe mensuelle allouée à une catégorie de dépenses
+type Budget struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"userId"`
+	Category   string    `json:"category"`
+	Amount     float64   `json:"amount"`     // Montant mensuel
+	Rollover   bool      `json:"rollover"`   // Report du reste sur le mois suivant
+	StartMonth time.Time `json:"startMonth"` // Premier mois budgété, point de départ des reports
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Envelope représente une enveloppe du budget base zéro, alimentée par les revenus
+// et consommée par les dépenses de sa catégorie
+type Envelope struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"userId"`
+	Name      string    `json:"name"` // Chiffré en BDD
+	Category  string    `json:"category"`
+	Goal      *float64  `json:"goal"` // Objectif mensuel facultatif
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// EnvelopeAllocation représente le montant affecté à une enveloppe pour un mois
+type EnvelopeAllocation struct {
+	ID         int64     `json:"id"`
+	UserID     int64     `json:"userId"`
+	EnvelopeID int64     `json:"envelopeId"`
+	Month      time.Time `json:"month"`
+	Amount     float64   `json:"amount"`
+}
+
+// EnvelopeMove représente un transfert explicite d'argent entre deux enveloppes
+type EnvelopeMove struct {
+	ID             int64     `json:"id"`
+	UserID         int64     `json:"userId"`
+	FromEnvelopeID int64     `json:"fromEnvelopeId"`
+	ToEnvelopeID   int64     `json:"toEnvelopeId"`
+	Amount         float64   `json:"amount"`
+	Month          time.Time `json:"month"`
+	CreatedAt      time.Time `json:"createdAt"`
 }
 
 // Authenticator représente une Passkey WebAuthn
@@ -0,0 +1,56 @@
+package db
+
+import "database/sql"
+
+// GetPocketsByUserID récupère les poches d'un utilisateur, groupées par compte
+func GetPocketsByUserID(userID int64) (map[int64][]Pocket, error) {
+	rows, err := DB.Query(`
+		SELECT id, account_id, name, amount, goal, color
+		FROM pockets WHERE user_id = ? ORDER BY position ASC, id ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pockets := make(map[int64][]Pocket)
+	for rows.Next() {
+		var p Pocket
+		var goal sql.NullFloat64
+		if err := rows.Scan(&p.ID, &p.AccountID, &p.Name, &p.Amount, &goal, &p.Color); err != nil {
+			return nil, err
+		}
+		if goal.Valid {
+			p.Goal = &goal.Float64
+		}
+		pockets[p.AccountID] = append(pockets[p.AccountID], p)
+	}
+
+	return pockets, rows.Err()
+}
+
+// ReplacePockets remplace la repartition d'un compte en poches
+func ReplacePockets(accountID, userID int64, pockets []Pocket) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM pockets WHERE account_id = ? AND user_id = ?`, accountID, userID)
+	if err != nil {
+		return err
+	}
+
+	for i, p := range pockets {
+		_, err = tx.Exec(`
+			INSERT INTO pockets (user_id, account_id, name, amount, goal, color, position)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+		`, userID, accountID, p.Name, p.Amount, p.Goal, p.Color, i)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,54 @@
+package db
+
+import "time"
+
+// GetRateSegmentsByUserID récupère les baremes de taux d'un utilisateur, groupés par compte
+func GetRateSegmentsByUserID(userID int64) (map[int64][]RateSegment, error) {
+	rows, err := DB.Query(`
+		SELECT id, account_id, effective_date, tier_min, rate
+		FROM rate_segments WHERE user_id = ? ORDER BY effective_date ASC, tier_min ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := make(map[int64][]RateSegment)
+	for rows.Next() {
+		var seg RateSegment
+		var effectiveDate int64
+		if err := rows.Scan(&seg.ID, &seg.AccountID, &effectiveDate, &seg.TierMin, &seg.Rate); err != nil {
+			return nil, err
+		}
+		seg.EffectiveDate = time.Unix(effectiveDate, 0)
+		schedules[seg.AccountID] = append(schedules[seg.AccountID], seg)
+	}
+
+	return schedules, rows.Err()
+}
+
+// ReplaceRateSchedule remplace le bareme de taux d'un compte
+func ReplaceRateSchedule(accountID, userID int64, segments []RateSegment) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM rate_segments WHERE account_id = ? AND user_id = ?`, accountID, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, seg := range segments {
+		_, err = tx.Exec(`
+			INSERT INTO rate_segments (user_id, account_id, effective_date, tier_min, rate)
+			VALUES (?, ?, ?, ?, ?)
+		`, userID, accountID, seg.EffectiveDate.Unix(), seg.TierMin, seg.Rate)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,77 @@
+package db
+
+import "time"
+
+// GetReconciliationsByAccount récupère les points de controle d'un compte, plus recents d'abord
+func GetReconciliationsByAccount(accountID, userID int64) ([]Reconciliation, error) {
+	rows, err := DB.Query(`
+		SELECT id, user_id, account_id, statement_date, statement_balance, opening_balance, created_at
+		FROM reconciliations WHERE account_id = ? AND user_id = ?
+		ORDER BY statement_date DESC, id DESC
+	`, accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var recs []Reconciliation
+	for rows.Next() {
+		var rec Reconciliation
+		var statementDate, createdAt int64
+		err := rows.Scan(&rec.ID, &rec.UserID, &rec.AccountID, &statementDate,
+			&rec.StatementBalance, &rec.OpeningBalance, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+		rec.StatementDate = time.Unix(statementDate, 0)
+		rec.CreatedAt = time.Unix(createdAt, 0)
+		recs = append(recs, rec)
+	}
+
+	return recs, rows.Err()
+}
+
+// CreateReconciliation enregistre un point de controle et y rattache les
+// transactions pointees jusqu'a la date du releve
+func CreateReconciliation(rec Reconciliation) (int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`
+		INSERT INTO reconciliations (user_id, account_id, statement_date, statement_balance, opening_balance, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, rec.UserID, rec.AccountID, rec.StatementDate.Unix(), rec.StatementBalance, rec.OpeningBalance, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE transactions SET reconciliation_id = ?
+		WHERE account_id = ? AND user_id = ? AND cleared = 1 AND reconciliation_id IS NULL AND date <= ?
+	`, id, rec.AccountID, rec.UserID, rec.StatementDate.Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	return id, tx.Commit()
+}
+
+// SetTransactionCleared pointe ou depointe une transaction non encore rapprochee.
+// Retourne false si la transaction est absente ou deja validee par un point de controle.
+func SetTransactionCleared(id, userID int64, cleared bool) (bool, error) {
+	res, err := DB.Exec(`
+		UPDATE transactions SET cleared = ? WHERE id = ? AND user_id = ? AND reconciliation_id IS NULL
+	`, cleared, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrRecurringAlreadyRun indique une operation recurrente deja executee ce mois-ci
+var ErrRecurringAlreadyRun = errors.New("operation deja executee ce mois-ci")
+
+// RecurringRun regroupe les ecritures d'une execution d'operation recurrente
+type RecurringRun struct {
+	Entries   []Transaction    // Ecritures simples (revenu ou depense)
+	Transfers [][2]Transaction // Virements (debit, credit), dont ceux de repartition
+}
+
+// RunRecurring enregistre l'execution mensuelle d'une operation recurrente dans une
+// seule transaction : date d'execution et ecritures. Comme pour toute ecriture, le solde
+// des comptes n'est pas modifie.
+// Retourne ErrRecurringAlreadyRun si l'operation a deja ete executee le mois de runDate.
+func RunRecurring(op RecurringOperation, runDate time.Time, run RecurringRun) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	monthStart := time.Date(runDate.Year(), runDate.Month(), 1, 0, 0, 0, 0, runDate.Location())
+	res, err := tx.Exec(`
+		UPDATE recurring_operations SET last_run_date = ?
+		WHERE id = ? AND user_id = ? AND (last_run_date IS NULL OR last_run_date < ?)
+	`, runDate.Unix(), op.ID, op.UserID, monthStart.Unix())
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrRecurringAlreadyRun
+	}
+
+	if err := insertTransactions(tx, run.Entries); err != nil {
+		return err
+	}
+	for _, pair := range run.Transfers {
+		if _, err := insertTransfer(tx, pair[0], pair[1]); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
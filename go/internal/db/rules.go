@@ -0,0 +1,55 @@
+package db
+
+import "time"
+
+// GetRulesByUserID récupère les regles de categorisation dans leur ordre d'application
+func GetRulesByUserID(userID int64) ([]CategorizationRule, error) {
+	rows, err := DB.Query(`
+		SELECT id, user_id, definition, position, is_active, created_at
+		FROM categorization_rules WHERE user_id = ? ORDER BY position ASC, id ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []CategorizationRule
+	for rows.Next() {
+		var r CategorizationRule
+		var createdAt int64
+		if err := rows.Scan(&r.ID, &r.UserID, &r.Definition, &r.Position, &r.IsActive, &createdAt); err != nil {
+			return nil, err
+		}
+		r.CreatedAt = time.Unix(createdAt, 0)
+		rules = append(rules, r)
+	}
+
+	return rules, rows.Err()
+}
+
+// CreateRule cree une regle de categorisation
+func CreateRule(userID int64, definition string, position int, isActive bool) (int64, error) {
+	res, err := DB.Exec(`
+		INSERT INTO categorization_rules (user_id, definition, position, is_active, created_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, userID, definition, position, isActive, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// UpdateRule met a jour une regle de categorisation
+func UpdateRule(id, userID int64, definition string, position int, isActive bool) error {
+	_, err := DB.Exec(`
+		UPDATE categorization_rules SET definition = ?, position = ?, is_active = ?
+		WHERE id = ? AND user_id = ?
+	`, definition, position, isActive, id, userID)
+	return err
+}
+
+// DeleteRule supprime une regle de categorisation
+func DeleteRule(id, userID int64) error {
+	_, err := DB.Exec(`DELETE FROM categorization_rules WHERE id = ? AND user_id = ?`, id, userID)
+	return err
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// execer est commun a *sql.DB et *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// replaceTokens remplace les mots-cles de recherche d'une transaction
+func replaceTokens(e execer, transactionID, userID int64, tokens []string) error {
+	if _, err := e.Exec(`DELETE FROM transaction_tokens WHERE transaction_id = ?`, transactionID); err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		_, err := e.Exec(`
+			INSERT OR IGNORE INTO transaction_tokens (transaction_id, user_id, token) VALUES (?, ?, ?)
+		`, transactionID, userID, token)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReplaceTransactionTokens remplace les mots-cles de recherche d'une transaction
+// (reindexation des transactions existantes)
+func ReplaceTransactionTokens(transactionID, userID int64, tokens []string) error {
+	return replaceTokens(DB, transactionID, userID, tokens)
+}
+
+// TransactionFilter decrit une recherche de transactions. Les champs nil sont ignores.
+type TransactionFilter struct {
+	Tokens    []string // Tous requis (mots-cles en index aveugle)
+	AccountID *int64
+	Category  *string // Categorie de la transaction ou d'une de ses ventilations
+	From      *time.Time
+	To        *time.Time
+	AmountMin *float64
+	AmountMax *float64
+	Limit     int
+}
+
+// SearchTransactions recherche les transactions d'un utilisateur, plus recentes d'abord
+func SearchTransactions(userID int64, f TransactionFilter) ([]Transaction, error) {
+	where := []string{"user_id = ?"}
+	args := []interface{}{userID}
+
+	if len(f.Tokens) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(f.Tokens)), ",")
+		where = append(where, `id IN (
+			SELECT transaction_id FROM transaction_tokens
+			WHERE user_id = ? AND token IN (`+placeholders+`)
+			GROUP BY transaction_id HAVING COUNT(DISTINCT token) = ?
+		)`)
+		args = append(args, userID)
+		for _, token := range f.Tokens {
+			args = append(args, token)
+		}
+		args = append(args, len(f.Tokens))
+	}
+	if f.AccountID != nil {
+		where = append(where, "account_id = ?")
+		args = append(args, *f.AccountID)
+	}
+	if f.Category != nil {
+		where = append(where, "(category = ? OR id IN (SELECT transaction_id FROM transaction_splits WHERE user_id = ? AND category = ?))")
+		args = append(args, *f.Category, userID, *f.Category)
+	}
+	if f.From != nil {
+		where = append(where, "date >= ?")
+		args = append(args, f.From.Unix())
+	}
+	if f.To != nil {
+		where = append(where, "date <= ?")
+		args = append(args, f.To.Unix())
+	}
+	if f.AmountMin != nil {
+		where = append(where, "amount >= ?")
+		args = append(args, *f.AmountMin)
+	}
+	if f.AmountMax != nil {
+		where = append(where, "amount <= ?")
+		args = append(args, *f.AmountMax)
+	}
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = 200
+	}
+	args = append(args, limit)
+
+	txs, err := queryTransactions(`
+		SELECT `+transactionColumns+`
+		FROM transactions WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY date DESC, id DESC LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	return txs, attachSplits(txs, userID)
+}
@@ -0,0 +1,86 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// GetSplitsByUserID récupère les ventilations d'un utilisateur, par transaction
+func GetSplitsByUserID(userID int64) (map[int64][]TransactionSplit, error) {
+	rows, err := DB.Query(`
+		SELECT id, transaction_id, category, amount, memo
+		FROM transaction_splits WHERE user_id = ? ORDER BY transaction_id, position, id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	splits := make(map[int64][]TransactionSplit)
+	for rows.Next() {
+		var s TransactionSplit
+		var category, memo sql.NullString
+		if err := rows.Scan(&s.ID, &s.TransactionID, &category, &s.Amount, &memo); err != nil {
+			return nil, err
+		}
+		if category.Valid {
+			s.Category = &category.String
+		}
+		if memo.Valid {
+			s.Memo = &memo.String
+		}
+		splits[s.TransactionID] = append(splits[s.TransactionID], s)
+	}
+
+	return splits, rows.Err()
+}
+
+// attachSplits associe leurs ventilations aux transactions
+func attachSplits(txs []Transaction, userID int64) error {
+	if len(txs) == 0 {
+		return nil
+	}
+	splits, err := GetSplitsByUserID(userID)
+	if err != nil {
+		return err
+	}
+	for i := range txs {
+		txs[i].Splits = splits[txs[i].ID]
+	}
+	return nil
+}
+
+// ReplaceSplits remplace la ventilation d'une transaction (vide pour la supprimer)
+func ReplaceSplits(transactionID, userID int64, splits []TransactionSplit) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var owner int64
+	err = tx.QueryRow(`SELECT user_id FROM transactions WHERE id = ? AND user_id = ?`, transactionID, userID).Scan(&owner)
+	if err == sql.ErrNoRows {
+		return errors.New("transaction non trouvee")
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM transaction_splits WHERE transaction_id = ? AND user_id = ?`, transactionID, userID)
+	if err != nil {
+		return err
+	}
+
+	for i, s := range splits {
+		_, err = tx.Exec(`
+			INSERT INTO transaction_splits (user_id, transaction_id, category, amount, memo, position)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, userID, transactionID, s.Category, s.Amount, s.Memo, i)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -69,6 +69,221 @@ func runMigrations() {
 	migrations := []string{
 		// Ajouter backup_eligible aux authenticators (pour go-webauthn)
 		`ALTER TABLE authenticators ADD COLUMN backup_eligible INTEGER DEFAULT 0`,
+		// Plus-values : methode de calcul du prix de revient (PRU ou FIFO)
+		`ALTER TABLE accounts ADD COLUMN cost_basis_method TEXT DEFAULT 'PRU'`,
+		// Plus-values : ordres d'achat/vente des comptes-titres
+		`CREATE TABLE IF NOT EXISTS trades (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			symbol TEXT NOT NULL,
+			side TEXT NOT NULL,
+			quantity REAL NOT NULL,
+			price REAL NOT NULL,
+			fees REAL NOT NULL DEFAULT 0,
+			date INTEGER NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_trades_account ON trades(account_id, date)`,
+		// Plus-values : derniers cours connus pour les plus-values latentes
+		`CREATE TABLE IF NOT EXISTS quotes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			symbol TEXT NOT NULL,
+			symbol_blind_index TEXT NOT NULL,
+			price REAL NOT NULL,
+			updated_at INTEGER NOT NULL,
+			UNIQUE(account_id, symbol_blind_index)
+		)`,
+		// Biens (immobilier, vehicule) : type de compte et regle de valorisation
+		`ALTER TABLE accounts ADD COLUMN kind TEXT DEFAULT 'STANDARD'`,
+		`ALTER TABLE accounts ADD COLUMN purchase_price REAL DEFAULT 0`,
+		`ALTER TABLE accounts ADD COLUMN purchase_date INTEGER`,
+		`ALTER TABLE accounts ADD COLUMN valuation_rule TEXT`,
+		`ALTER TABLE accounts ADD COLUMN valuation_rate REAL DEFAULT 0`,
+		`ALTER TABLE accounts ADD COLUMN depreciation_years INTEGER DEFAULT 0`,
+		// Frais de gestion (assurance-vie, fonds)
+		`ALTER TABLE accounts ADD COLUMN fee_annual_rate REAL DEFAULT 0`,
+		`ALTER TABLE accounts ADD COLUMN fee_deposit_rate REAL DEFAULT 0`,
+		`ALTER TABLE accounts ADD COLUMN fee_fixed_yearly REAL DEFAULT 0`,
+		// Evenements ponctuels prevus pour les projections
+		`CREATE TABLE IF NOT EXISTS planned_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			amount REAL NOT NULL,
+			description TEXT NOT NULL,
+			date INTEGER NOT NULL
+		)`,
+		// Bareme de taux par compte (periodes et tranches de solde)
+		`CREATE TABLE IF NOT EXISTS rate_segments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			effective_date INTEGER NOT NULL,
+			tier_min REAL NOT NULL DEFAULT 0,
+			rate REAL NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_rate_segments_account ON rate_segments(account_id, effective_date)`,
+		// Comptes a terme : taux, echeance et politique de renouvellement
+		`ALTER TABLE accounts ADD COLUMN term_rate REAL DEFAULT 0`,
+		`ALTER TABLE accounts ADD COLUMN term_start_date INTEGER`,
+		`ALTER TABLE accounts ADD COLUMN term_maturity_date INTEGER`,
+		`ALTER TABLE accounts ADD COLUMN term_rollover TEXT`,
+		`ALTER TABLE accounts ADD COLUMN term_target_account_id INTEGER`,
+		// Transactions (historique des comptes, alimente par les imports)
+		`CREATE TABLE IF NOT EXISTS transactions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			amount REAL NOT NULL,
+			description TEXT NOT NULL,
+			category TEXT,
+			date INTEGER NOT NULL,
+			created_at INTEGER NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_transactions_account ON transactions(account_id, date)`,
+		// Import CSV : correspondances de colonnes enregistrees par banque
+		`CREATE TABLE IF NOT EXISTS import_mappings (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name TEXT NOT NULL,
+			config TEXT NOT NULL,
+			created_at INTEGER NOT NULL
+		)`,
+		// Import OFX : identifiant banque (FITID) en index aveugle pour ignorer les doublons
+		`ALTER TABLE transactions ADD COLUMN external_ref TEXT`,
+		`CREATE INDEX IF NOT EXISTS idx_transactions_external_ref ON transactions(account_id, external_ref)`,
+		// Detection des doublons a l'import : empreinte HMAC (reference banque ou date + montant + libelle)
+		`ALTER TABLE transactions ADD COLUMN fingerprint TEXT`,
+		`CREATE INDEX IF NOT EXISTS idx_transactions_fingerprint ON transactions(account_id, fingerprint)`,
+		// Categorisation automatique : tiers et etiquettes chiffres, regles utilisateur chiffrees
+		`ALTER TABLE transactions ADD COLUMN payee TEXT`,
+		`ALTER TABLE transactions ADD COLUMN tags TEXT`,
+		`CREATE TABLE IF NOT EXISTS categorization_rules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			definition TEXT NOT NULL,
+			position INTEGER NOT NULL DEFAULT 0,
+			is_active INTEGER NOT NULL DEFAULT 1,
+			created_at INTEGER NOT NULL
+		)`,
+		// Rapprochement bancaire : transactions pointees et points de controle par compte
+		`ALTER TABLE transactions ADD COLUMN cleared INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE transactions ADD COLUMN reconciliation_id INTEGER`,
+		`CREATE TABLE IF NOT EXISTS reconciliations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			statement_date INTEGER NOT NULL,
+			statement_balance REAL NOT NULL,
+			opening_balance REAL NOT NULL,
+			created_at INTEGER NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_reconciliations_account ON reconciliations(account_id, statement_date)`,
+		// Ventilation d'une transaction sur plusieurs categories
+		`CREATE TABLE IF NOT EXISTS transaction_splits (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			transaction_id INTEGER NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+			category TEXT,
+			amount REAL NOT NULL,
+			memo TEXT,
+			position INTEGER NOT NULL DEFAULT 0
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_transaction_splits_transaction ON transaction_splits(transaction_id)`,
+		// Virements : les deux ecritures (debit et credit) sont liees entre elles
+		`ALTER TABLE transactions ADD COLUMN linked_id INTEGER`,
+		// Recherche : mots-cles des libelles et tiers en index aveugle HMAC
+		`CREATE TABLE IF NOT EXISTS transaction_tokens (
+			transaction_id INTEGER NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			token TEXT NOT NULL,
+			PRIMARY KEY (transaction_id, token)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_transaction_tokens_user ON transaction_tokens(user_id, token)`,
+		// Pieces jointes chiffrees sur disque, rattachees a une transaction ou a un compte
+		`CREATE TABLE IF NOT EXISTS attachments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			transaction_id INTEGER REFERENCES transactions(id) ON DELETE CASCADE,
+			account_id INTEGER REFERENCES accounts(id) ON DELETE CASCADE,
+			filename TEXT NOT NULL,
+			content_type TEXT NOT NULL,
+			size INTEGER NOT NULL,
+			storage_name TEXT NOT NULL UNIQUE,
+			created_at INTEGER NOT NULL
+		)`,
+		// Categorie budgetaire des operations recurrentes
+		`ALTER TABLE recurring_operations ADD COLUMN category TEXT`,
+		// Budgets mensuels par categorie, avec report optionnel du reste
+		`CREATE TABLE IF NOT EXISTS budgets (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			category TEXT NOT NULL,
+			amount REAL NOT NULL,
+			rollover INTEGER NOT NULL DEFAULT 0,
+			start_month INTEGER NOT NULL,
+			created_at INTEGER NOT NULL,
+			UNIQUE(user_id, category)
+		)`,
+		// Mode de budget : par categorie (CATEGORY) ou par enveloppes (ENVELOPE)
+		`ALTER TABLE users ADD COLUMN budget_mode TEXT NOT NULL DEFAULT 'CATEGORY'`,
+		// Enveloppes, affectations mensuelles et transferts entre enveloppes
+		`CREATE TABLE IF NOT EXISTS envelopes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			name TEXT NOT NULL,
+			category TEXT NOT NULL,
+			goal REAL,
+			position INTEGER NOT NULL DEFAULT 0,
+			created_at INTEGER NOT NULL,
+			UNIQUE(user_id, category)
+		)`,
+		`CREATE TABLE IF NOT EXISTS envelope_allocations (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			envelope_id INTEGER NOT NULL REFERENCES envelopes(id) ON DELETE CASCADE,
+			month INTEGER NOT NULL,
+			amount REAL NOT NULL,
+			UNIQUE(envelope_id, month)
+		)`,
+		`CREATE TABLE IF NOT EXISTS envelope_moves (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			from_envelope_id INTEGER NOT NULL REFERENCES envelopes(id) ON DELETE CASCADE,
+			to_envelope_id INTEGER NOT NULL REFERENCES envelopes(id) ON DELETE CASCADE,
+			amount REAL NOT NULL,
+			month INTEGER NOT NULL,
+			created_at INTEGER NOT NULL
+		)`,
+		// Poches chiffrees repartissant le solde d'un compte
+		`CREATE TABLE IF NOT EXISTS pockets (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			name TEXT NOT NULL,
+			amount REAL NOT NULL,
+			goal REAL,
+			color TEXT NOT NULL DEFAULT '',
+			position INTEGER NOT NULL DEFAULT 0
+		)`,
+		// Taux et mensualite minimale des comptes de dette
+		`ALTER TABLE accounts ADD COLUMN debt_rate REAL`,
+		`ALTER TABLE accounts ADD COLUMN debt_min_payment REAL`,
+		// Regles de repartition des revenus recurrents vers d'autres comptes
+		`CREATE TABLE IF NOT EXISTS allocation_rules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+			recurring_id INTEGER NOT NULL REFERENCES recurring_operations(id) ON DELETE CASCADE,
+			to_account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+			percent REAL NOT NULL,
+			position INTEGER NOT NULL DEFAULT 0,
+			created_at INTEGER NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_allocation_rules_recurring ON allocation_rules(recurring_id)`,
+		// Date de creation des operations recurrentes : aucune execution avant elle
+		`ALTER TABLE recurring_operations ADD COLUMN created_at INTEGER`,
 	}
 
 	for _, migration := range migrations {
@@ -190,13 +405,108 @@ func GetUserByID(id int64) (*User, error) {
 	return &user, nil
 }
 
+// accountColumns liste les colonnes lues par scanAccount
+const accountColumns = `
+		id, user_id, name, balance, color, position, updated_at,
+		is_yield_active, yield_type, yield_min, yield_max,
+		yield_frequency, payout_frequency, last_yield_date,
+		reinvestment_rate, target_account_id, cost_basis_method,
+		kind, purchase_price, purchase_date, valuation_rule, valuation_rate,
+		depreciation_years, fee_annual_rate, fee_deposit_rate, fee_fixed_yearly,
+		term_rate, term_start_date, term_maturity_date, term_rollover, term_target_account_id,
+		debt_rate, debt_min_payment`
+
+// rowScanner est implemente par *sql.Row et *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAccount lit un compte dans l'ordre de accountColumns
+func scanAccount(row rowScanner) (Account, error) {
+	var acc Account
+	var updatedAt, lastYieldDate, purchaseDate sql.NullInt64
+	var targetAccountID, depreciationYears sql.NullInt64
+	var yieldType, yieldFreq, payoutFreq, costBasisMethod, kind, valuationRule sql.NullString
+	var purchasePrice, valuationRate sql.NullFloat64
+	var feeAnnualRate, feeDepositRate, feeFixedYearly sql.NullFloat64
+	var termRate sql.NullFloat64
+	var termStartDate, termMaturityDate, termTargetAccountID sql.NullInt64
+	var termRollover sql.NullString
+	var debtRate, debtMinPayment sql.NullFloat64
+
+	err := row.Scan(
+		&acc.ID, &acc.UserID, &acc.Name, &acc.Balance, &acc.Color, &acc.Position,
+		&updatedAt, &acc.IsYieldActive, &yieldType, &acc.YieldMin, &acc.YieldMax,
+		&yieldFreq, &payoutFreq, &lastYieldDate, &acc.ReinvestmentRate, &targetAccountID,
+		&costBasisMethod, &kind, &purchasePrice, &purchaseDate, &valuationRule, &valuationRate,
+		&depreciationYears, &feeAnnualRate, &feeDepositRate, &feeFixedYearly,
+		&termRate, &termStartDate, &termMaturityDate, &termRollover, &termTargetAccountID,
+		&debtRate, &debtMinPayment,
+	)
+	if err != nil {
+		return acc, err
+	}
+
+	if updatedAt.Valid {
+		acc.UpdatedAt = time.Unix(updatedAt.Int64, 0)
+	}
+	if lastYieldDate.Valid {
+		t := time.Unix(lastYieldDate.Int64, 0)
+		acc.LastYieldDate = &t
+	}
+	if targetAccountID.Valid {
+		acc.TargetAccountID = &targetAccountID.Int64
+	}
+	if yieldType.Valid {
+		acc.YieldType = yieldType.String
+	}
+	if yieldFreq.Valid {
+		acc.YieldFrequency = yieldFreq.String
+	}
+	if payoutFreq.Valid {
+		acc.PayoutFrequency = payoutFreq.String
+	}
+	acc.CostBasisMethod = "PRU"
+	if costBasisMethod.Valid && costBasisMethod.String != "" {
+		acc.CostBasisMethod = costBasisMethod.String
+	}
+	acc.Kind = "STANDARD"
+	if kind.Valid && kind.String != "" {
+		acc.Kind = kind.String
+	}
+	if purchaseDate.Valid {
+		t := time.Unix(purchaseDate.Int64, 0)
+		acc.PurchaseDate = &t
+	}
+	acc.PurchasePrice = purchasePrice.Float64
+	acc.ValuationRule = valuationRule.String
+	acc.ValuationRate = valuationRate.Float64
+	acc.DepreciationYears = int(depreciationYears.Int64)
+	acc.FeeAnnualRate = feeAnnualRate.Float64
+	acc.FeeDepositRate = feeDepositRate.Float64
+	acc.FeeFixedYearly = feeFixedYearly.Float64
+	acc.TermRate = termRate.Float64
+	if termStartDate.Valid {
+		t := time.Unix(termStartDate.Int64, 0)
+		acc.TermStartDate = &t
+	}
+	if termMaturityDate.Valid {
+		t := time.Unix(termMaturityDate.Int64, 0)
+		acc.TermMaturityDate = &t
+	}
+	acc.TermRollover = termRollover.String
+	if termTargetAccountID.Valid {
+		acc.TermTargetAccountID = &termTargetAccountID.Int64
+	}
+	acc.DebtRate = debtRate.Float64
+	acc.DebtMinPayment = debtMinPayment.Float64
+
+	return acc, nil
+}
+
 // GetAccountsByUserID récupère tous les comptes d'un utilisateur
 func GetAccountsByUserID(userID int64) ([]Account, error) {
-	rows, err := DB.Query(`
-		SELECT id, user_id, name, balance, color, position, updated_at,
-		       is_yield_active, yield_type, yield_min, yield_max,
-		       yield_frequency, payout_frequency, last_yield_date,
-		       reinvestment_rate, target_account_id
+	rows, err := DB.Query(`SELECT `+accountColumns+`
 		FROM accounts WHERE user_id = ? ORDER BY position ASC
 	`, userID)
 	if err != nil {
@@ -206,51 +516,69 @@ func GetAccountsByUserID(userID int64) ([]Account, error) {
 
 	var accounts []Account
 	for rows.Next() {
-		var acc Account
-		var updatedAt, lastYieldDate sql.NullInt64
-		var targetAccountID sql.NullInt64
-		var yieldType, yieldFreq, payoutFreq sql.NullString
-
-		err := rows.Scan(
-			&acc.ID, &acc.UserID, &acc.Name, &acc.Balance, &acc.Color, &acc.Position,
-			&updatedAt, &acc.IsYieldActive, &yieldType, &acc.YieldMin, &acc.YieldMax,
-			&yieldFreq, &payoutFreq, &lastYieldDate, &acc.ReinvestmentRate, &targetAccountID,
-		)
+		acc, err := scanAccount(rows)
 		if err != nil {
 			return nil, err
 		}
+		accounts = append(accounts, acc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-		if updatedAt.Valid {
-			acc.UpdatedAt = time.Unix(updatedAt.Int64, 0)
-		}
-		if lastYieldDate.Valid {
-			t := time.Unix(lastYieldDate.Int64, 0)
-			acc.LastYieldDate = &t
-		}
-		if targetAccountID.Valid {
-			acc.TargetAccountID = &targetAccountID.Int64
-		}
-		if yieldType.Valid {
-			acc.YieldType = yieldType.String
-		}
-		if yieldFreq.Valid {
-			acc.YieldFrequency = yieldFreq.String
-		}
-		if payoutFreq.Valid {
-			acc.PayoutFrequency = payoutFreq.String
-		}
+	// Rattacher les baremes de taux
+	schedules, err := GetRateSegmentsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range accounts {
+		accounts[i].RateSchedule = schedules[accounts[i].ID]
+	}
 
-		accounts = append(accounts, acc)
+	// Rattacher les poches
+	pockets, err := GetPocketsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range accounts {
+		accounts[i].Pockets = pockets[accounts[i].ID]
+	}
+
+	return accounts, nil
+}
+
+// GetAccountByID récupère un compte en vérifiant qu'il appartient à l'utilisateur
+func GetAccountByID(id, userID int64) (*Account, error) {
+	acc, err := scanAccount(DB.QueryRow(`SELECT `+accountColumns+`
+		FROM accounts WHERE id = ? AND user_id = ?
+	`, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return accounts, rows.Err()
+	schedules, err := GetRateSegmentsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	acc.RateSchedule = schedules[acc.ID]
+
+	pockets, err := GetPocketsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	acc.Pockets = pockets[acc.ID]
+
+	return &acc, nil
 }
 
 // GetRecurringByUserID récupère toutes les opérations récurrentes d'un utilisateur
 func GetRecurringByUserID(userID int64) ([]RecurringOperation, error) {
 	rows, err := DB.Query(`
 		SELECT id, user_id, account_id, to_account_id, amount, description,
-		       day_of_month, last_run_date, is_active
+		       day_of_month, last_run_date, is_active, category, created_at
 		FROM recurring_operations WHERE user_id = ? ORDER BY day_of_month ASC
 	`, userID)
 	if err != nil {
@@ -262,11 +590,11 @@ func GetRecurringByUserID(userID int64) ([]RecurringOperation, error) {
 	for rows.Next() {
 		var op RecurringOperation
 		var toAccountID sql.NullInt64
-		var lastRunDate sql.NullInt64
+		var lastRunDate, createdAt sql.NullInt64
 
 		err := rows.Scan(
 			&op.ID, &op.UserID, &op.AccountID, &toAccountID, &op.Amount,
-			&op.Description, &op.DayOfMonth, &lastRunDate, &op.IsActive,
+			&op.Description, &op.DayOfMonth, &lastRunDate, &op.IsActive, &op.Category, &createdAt,
 		)
 		if err != nil {
 			return nil, err
@@ -279,6 +607,10 @@ func GetRecurringByUserID(userID int64) ([]RecurringOperation, error) {
 			t := time.Unix(lastRunDate.Int64, 0)
 			op.LastRunDate = &t
 		}
+		if createdAt.Valid {
+			t := time.Unix(createdAt.Int64, 0)
+			op.CreatedAt = &t
+		}
 
 		ops = append(ops, op)
 	}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// CreateTrade enregistre un ordre d'achat ou de vente
+func CreateTrade(userID, accountID int64, symbol, side string, quantity, price, fees float64, date time.Time) error {
+	_, err := DB.Exec(`
+		INSERT INTO trades (user_id, account_id, symbol, side, quantity, price, fees, date)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, userID, accountID, symbol, side, quantity, price, fees, date.Unix())
+	return err
+}
+
+// GetTradesByAccount récupère les ordres d'un compte par ordre chronologique
+func GetTradesByAccount(accountID, userID int64) ([]Trade, error) {
+	rows, err := DB.Query(`
+		SELECT id, user_id, account_id, symbol, side, quantity, price, fees, date
+		FROM trades WHERE account_id = ? AND user_id = ? ORDER BY date ASC, id ASC
+	`, accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trades []Trade
+	for rows.Next() {
+		var t Trade
+		var date int64
+		err := rows.Scan(&t.ID, &t.UserID, &t.AccountID, &t.Symbol, &t.Side,
+			&t.Quantity, &t.Price, &t.Fees, &date)
+		if err != nil {
+			return nil, err
+		}
+		t.Date = time.Unix(date, 0)
+		trades = append(trades, t)
+	}
+
+	return trades, rows.Err()
+}
+
+// DeleteTrade supprime un ordre
+func DeleteTrade(id, accountID, userID int64) error {
+	_, err := DB.Exec(`DELETE FROM trades WHERE id = ? AND account_id = ? AND user_id = ?`, id, accountID, userID)
+	return err
+}
+
+// UpsertQuote enregistre le dernier cours d'un titre (symbol chiffré, blindIndex pour l'unicité)
+func UpsertQuote(userID, accountID int64, symbol, blindIndex string, price float64) error {
+	_, err := DB.Exec(`
+		INSERT INTO quotes (user_id, account_id, symbol, symbol_blind_index, price, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(account_id, symbol_blind_index) DO UPDATE SET price = excluded.price, updated_at = excluded.updated_at
+	`, userID, accountID, symbol, blindIndex, price, time.Now().Unix())
+	return err
+}
+
+// GetQuotesByAccount récupère les cours connus d'un compte
+func GetQuotesByAccount(accountID, userID int64) ([]Quote, error) {
+	rows, err := DB.Query(`
+		SELECT id, account_id, symbol, price, updated_at
+		FROM quotes WHERE account_id = ? AND user_id = ?
+	`, accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var quotes []Quote
+	for rows.Next() {
+		var q Quote
+		var updatedAt sql.NullInt64
+		if err := rows.Scan(&q.ID, &q.AccountID, &q.Symbol, &q.Price, &updatedAt); err != nil {
+			return nil, err
+		}
+		if updatedAt.Valid {
+			q.UpdatedAt = time.Unix(updatedAt.Int64, 0)
+		}
+		quotes = append(quotes, q)
+	}
+
+	return quotes, rows.Err()
+}
+
+// UpdateAccountCostBasisMethod change la méthode de calcul du prix de revient
+func UpdateAccountCostBasisMethod(id, userID int64, method string) error {
+	_, err := DB.Exec(`
+		UPDATE accounts SET cost_basis_method = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`, method, time.Now().Unix(), id, userID)
+	return err
+}
@@ -0,0 +1,449 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// CreateTransactions insere un lot de transactions dans une seule transaction SQL
+func CreateTransactions(txs []Transaction) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertTransactions(tx, txs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MigratedAccount est un compte a creer lors d'une migration, avec son historique
+type MigratedAccount struct {
+	Key          string // Identifie le compte pour les operations recurrentes
+	Name         string // Chiffre
+	Balance      float64
+	Color        string
+	Position     int
+	Transactions []Transaction
+}
+
+// MigratedRecurring est une operation recurrente a creer lors d'une migration. Les
+// cles designent un compte migre ; a defaut, AccountID et ToAccountID de l'operation
+// designent un compte existant.
+type MigratedRecurring struct {
+	Operation    RecurringOperation
+	AccountKey   string
+	ToAccountKey string
+}
+
+// CreateMigration enregistre une migration depuis un autre logiciel dans une seule
+// transaction : les comptes et leur historique puis les operations recurrentes.
+// Retourne les IDs des comptes crees.
+func CreateMigration(userID int64, accounts []MigratedAccount, recurrings []MigratedRecurring) ([]int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Unix()
+	ids := make(map[string]int64, len(accounts))
+	created := make([]int64, 0, len(accounts))
+	for _, a := range accounts {
+		res, err := tx.Exec(`
+			INSERT INTO accounts (user_id, name, balance, color, position, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, userID, a.Name, a.Balance, a.Color, a.Position, now)
+		if err != nil {
+			return nil, err
+		}
+		accountID, err := res.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		ids[a.Key] = accountID
+		created = append(created, accountID)
+
+		for i := range a.Transactions {
+			a.Transactions[i].UserID = userID
+			a.Transactions[i].AccountID = accountID
+		}
+		if err := insertTransactions(tx, a.Transactions); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, r := range recurrings {
+		op := r.Operation
+		if id, ok := ids[r.AccountKey]; ok && r.AccountKey != "" {
+			op.AccountID = id
+		}
+		if id, ok := ids[r.ToAccountKey]; ok && r.ToAccountKey != "" {
+			op.ToAccountID = &id
+		}
+		_, err := tx.Exec(`
+			INSERT INTO recurring_operations (user_id, account_id, to_account_id, description, amount, day_of_month, created_at, is_active, category)
+			VALUES (?, ?, ?, ?, ?, ?, ?, 1, ?)
+		`, userID, op.AccountID, op.ToAccountID, op.Description, op.Amount, op.DayOfMonth, now, op.Category)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return created, tx.Commit()
+}
+
+func insertTransactions(tx *sql.Tx, txs []Transaction) error {
+	stmt, err := tx.Prepare(`
+		INSERT INTO transactions (user_id, account_id, amount, description, category, payee, tags, date, external_ref, fingerprint, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	now := time.Now().Unix()
+	for _, t := range txs {
+		res, err := stmt.Exec(t.UserID, t.AccountID, t.Amount, t.Description, t.Category, t.Payee, t.Tags, t.Date.Unix(), t.ExternalRef, t.Fingerprint, now)
+		if err != nil {
+			return err
+		}
+		id, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		if err := replaceTokens(tx, id, t.UserID, t.SearchTokens); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transactionColumns liste les colonnes lues par scanTransaction
+const transactionColumns = `id, user_id, account_id, amount, description, category, payee, tags, date, cleared, reconciliation_id, linked_id, created_at`
+
+func scanTransaction(row rowScanner) (Transaction, error) {
+	var t Transaction
+	var category, payee, tags sql.NullString
+	var date, createdAt int64
+	var reconciliationID, linkedID sql.NullInt64
+	err := row.Scan(&t.ID, &t.UserID, &t.AccountID, &t.Amount, &t.Description,
+		&category, &payee, &tags, &date, &t.Cleared, &reconciliationID, &linkedID, &createdAt)
+	if err != nil {
+		return t, err
+	}
+	if category.Valid {
+		t.Category = &category.String
+	}
+	if payee.Valid {
+		t.Payee = &payee.String
+	}
+	if tags.Valid {
+		t.Tags = &tags.String
+	}
+	if reconciliationID.Valid {
+		t.ReconciliationID = &reconciliationID.Int64
+	}
+	if linkedID.Valid {
+		t.LinkedID = &linkedID.Int64
+	}
+	t.Date = time.Unix(date, 0)
+	t.CreatedAt = time.Unix(createdAt, 0)
+	return t, nil
+}
+
+func queryTransactions(query string, args ...interface{}) ([]Transaction, error) {
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var txs []Transaction
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return nil, err
+		}
+		txs = append(txs, t)
+	}
+
+	return txs, rows.Err()
+}
+
+// GetTransactionsByAccount récupère les transactions d'un compte, plus récentes d'abord
+func GetTransactionsByAccount(accountID, userID int64) ([]Transaction, error) {
+	txs, err := queryTransactions(`
+		SELECT `+transactionColumns+`
+		FROM transactions WHERE account_id = ? AND user_id = ? ORDER BY date DESC, id DESC
+	`, accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return txs, attachSplits(txs, userID)
+}
+
+// GetTransactionsByUserID récupère toutes les transactions d'un utilisateur
+func GetTransactionsByUserID(userID int64) ([]Transaction, error) {
+	txs, err := queryTransactions(`
+		SELECT `+transactionColumns+`
+		FROM transactions WHERE user_id = ? ORDER BY date DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return txs, attachSplits(txs, userID)
+}
+
+// GetTransactionByID récupère une transaction de l'utilisateur (nil si absente)
+func GetTransactionByID(id, userID int64) (*Transaction, error) {
+	t, err := scanTransaction(DB.QueryRow(`
+		SELECT `+transactionColumns+`
+		FROM transactions WHERE id = ? AND user_id = ?
+	`, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	txs := []Transaction{t}
+	if err := attachSplits(txs, userID); err != nil {
+		return nil, err
+	}
+	return &txs[0], nil
+}
+
+// CreateTransaction insere une transaction saisie manuellement et retourne son ID
+func CreateTransaction(t Transaction) (int64, error) {
+	res, err := DB.Exec(`
+		INSERT INTO transactions (user_id, account_id, amount, description, category, payee, tags, date, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, t.UserID, t.AccountID, t.Amount, t.Description, t.Category, t.Payee, t.Tags, t.Date.Unix(), time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return id, replaceTokens(DB, id, t.UserID, t.SearchTokens)
+}
+
+// UpdateTransaction met a jour une transaction. Pour un virement, l'autre ecriture
+// recoit dans la meme transaction le montant oppose, le libelle, la date et les
+// mots-cles de recherche.
+func UpdateTransaction(t Transaction) error {
+	tx, err := DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE transactions SET amount = ?, description = ?, category = ?, payee = ?, tags = ?, date = ?
+		WHERE id = ? AND user_id = ?
+	`, t.Amount, t.Description, t.Category, t.Payee, t.Tags, t.Date.Unix(), t.ID, t.UserID)
+	if err != nil {
+		return err
+	}
+	if t.SearchTokens != nil {
+		if err := replaceTokens(tx, t.ID, t.UserID, t.SearchTokens); err != nil {
+			return err
+		}
+	}
+
+	if t.LinkedID != nil {
+		_, err = tx.Exec(`
+			UPDATE transactions SET amount = ?, description = ?, date = ? WHERE id = ? AND user_id = ?
+		`, -t.Amount, t.Description, t.Date.Unix(), *t.LinkedID, t.UserID)
+		if err != nil {
+			return err
+		}
+		if err := replaceTokens(tx, *t.LinkedID, t.UserID, t.SearchTokens); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UpdateTransactionClassification met a jour la categorie, le tiers, les etiquettes
+// et les mots-cles de recherche
+func UpdateTransactionClassification(id, userID int64, category, payee, tags *string, tokens []string) error {
+	_, err := DB.Exec(`
+		UPDATE transactions SET category = ?, payee = ?, tags = ? WHERE id = ? AND user_id = ?
+	`, category, payee, tags, id, userID)
+	if err != nil {
+		return err
+	}
+	return replaceTokens(DB, id, userID, tokens)
+}
+
+// DeleteTransaction supprime une transaction, et l'autre ecriture s'il s'agit d'un virement
+func DeleteTransaction(id, userID int64) error {
+	_, err := DB.Exec(`
+		DELETE FROM transactions WHERE user_id = ? AND (id = ? OR linked_id = ?)
+	`, userID, id, id)
+	return err
+}
+
+// CreateTransfer enregistre un virement : le debit du compte source et le credit
+// du compte destination, lies l'un a l'autre. Retourne l'ID du debit.
+func CreateTransfer(debit, credit Transaction) (int64, error) {
+	tx, err := DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, err := insertTransfer(tx, debit, credit)
+	if err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// insertTransfer insere les deux ecritures d'un virement et les lie entre elles
+func insertTransfer(tx *sql.Tx, debit, credit Transaction) (int64, error) {
+	now := time.Now().Unix()
+	ids := make([]int64, 2)
+	for i, t := range []Transaction{debit, credit} {
+		res, err := tx.Exec(`
+			INSERT INTO transactions (user_id, account_id, amount, description, date, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, t.UserID, t.AccountID, t.Amount, t.Description, t.Date.Unix(), now)
+		if err != nil {
+			return 0, err
+		}
+		if ids[i], err = res.LastInsertId(); err != nil {
+			return 0, err
+		}
+		if err := replaceTokens(tx, ids[i], t.UserID, t.SearchTokens); err != nil {
+			return 0, err
+		}
+	}
+
+	_, err := tx.Exec(`UPDATE transactions SET linked_id = ? WHERE id = ?`, ids[1], ids[0])
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.Exec(`UPDATE transactions SET linked_id = ? WHERE id = ?`, ids[0], ids[1])
+	if err != nil {
+		return 0, err
+	}
+
+	return ids[0], nil
+}
+
+// GetTransactionsForMatching récupère les transactions d'une periode avec leurs
+// identifiants d'import, pour rapprocher un releve de l'historique existant
+func GetTransactionsForMatching(accountID, userID int64, from, to time.Time) ([]Transaction, error) {
+	rows, err := DB.Query(`
+		SELECT id, amount, date, external_ref, fingerprint
+		FROM transactions
+		WHERE account_id = ? AND user_id = ? AND date >= ? AND date <= ?
+		ORDER BY date ASC, id ASC
+	`, accountID, userID, from.Unix(), to.Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var txs []Transaction
+	for rows.Next() {
+		var t Transaction
+		var date int64
+		var externalRef, fingerprint sql.NullString
+		if err := rows.Scan(&t.ID, &t.Amount, &date, &externalRef, &fingerprint); err != nil {
+			return nil, err
+		}
+		t.AccountID = accountID
+		t.UserID = userID
+		t.Date = time.Unix(date, 0)
+		if externalRef.Valid {
+			t.ExternalRef = &externalRef.String
+		}
+		if fingerprint.Valid {
+			t.Fingerprint = &fingerprint.String
+		}
+		txs = append(txs, t)
+	}
+
+	return txs, rows.Err()
+}
+
+// MergeImportedTransaction rattache une ligne de releve a une transaction existante :
+// la saisie est conservee, seules la categorie manquante et les identifiants d'import sont completes
+func MergeImportedTransaction(id, userID int64, category, externalRef, fingerprint *string) error {
+	_, err := DB.Exec(`
+		UPDATE transactions
+		SET category = COALESCE(category, ?), external_ref = COALESCE(external_ref, ?), fingerprint = ?
+		WHERE id = ? AND user_id = ?
+	`, category, externalRef, fingerprint, id, userID)
+	return err
+}
+
+// CreateImportMapping enregistre une correspondance de colonnes CSV
+func CreateImportMapping(userID int64, name, config string) (int64, error) {
+	res, err := DB.Exec(`
+		INSERT INTO import_mappings (user_id, name, config, created_at)
+		VALUES (?, ?, ?, ?)
+	`, userID, name, config, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetImportMappingsByUserID récupère les correspondances CSV d'un utilisateur
+func GetImportMappingsByUserID(userID int64) ([]ImportMapping, error) {
+	rows, err := DB.Query(`
+		SELECT id, user_id, name, config, created_at
+		FROM import_mappings WHERE user_id = ? ORDER BY id ASC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mappings []ImportMapping
+	for rows.Next() {
+		var m ImportMapping
+		var createdAt int64
+		if err := rows.Scan(&m.ID, &m.UserID, &m.Name, &m.Config, &createdAt); err != nil {
+			return nil, err
+		}
+		m.CreatedAt = time.Unix(createdAt, 0)
+		mappings = append(mappings, m)
+	}
+
+	return mappings, rows.Err()
+}
+
+// GetImportMappingByID récupère une correspondance CSV de l'utilisateur
+func GetImportMappingByID(id, userID int64) (*ImportMapping, error) {
+	var m ImportMapping
+	var createdAt int64
+	err := DB.QueryRow(`
+		SELECT id, user_id, name, config, created_at
+		FROM import_mappings WHERE id = ? AND user_id = ?
+	`, id, userID).Scan(&m.ID, &m.UserID, &m.Name, &m.Config, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.CreatedAt = time.Unix(createdAt, 0)
+	return &m, nil
+}
+
+// DeleteImportMapping supprime une correspondance CSV
+func DeleteImportMapping(id, userID int64) error {
+	_, err := DB.Exec(`DELETE FROM import_mappings WHERE id = ? AND user_id = ?`, id, userID)
+	return err
+}
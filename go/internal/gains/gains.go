@@ -0,0 +1,238 @@
+// Package gains calcule les plus-values realisees et latentes d'un compte-titres
+package gains
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"pilot-finance/internal/db"
+)
+
+// Methodes de calcul du prix de revient
+const (
+	MethodPRU  = "PRU"  // Prix de revient unitaire moyen pondere
+	MethodFIFO = "FIFO" // Premier entre, premier sorti
+)
+
+// ErrOversell est retourne quand une vente depasse la quantite detenue
+var ErrOversell = errors.New("vente superieure a la quantite detenue")
+
+// quantityEpsilon absorbe les erreurs d'arrondi sur les quantites fractionnaires
+const quantityEpsilon = 1e-9
+
+// Position represente une ligne encore detenue
+type Position struct {
+	Symbol      string   `json:"symbol"`
+	Quantity    float64  `json:"quantity"`
+	CostBasis   float64  `json:"costBasis"`
+	AverageCost float64  `json:"averageCost"`
+	Price       *float64 `json:"price"`
+	MarketValue *float64 `json:"marketValue"`
+	Unrealized  *float64 `json:"unrealized"`
+}
+
+// Realization represente la plus-value d'une vente
+type Realization struct {
+	TradeID   int64   `json:"tradeId"`
+	Symbol    string  `json:"symbol"`
+	Date      string  `json:"date"`
+	Year      int     `json:"year"`
+	Quantity  float64 `json:"quantity"`
+	Proceeds  float64 `json:"proceeds"`
+	CostBasis float64 `json:"costBasis"`
+	Gain      float64 `json:"gain"`
+}
+
+// YearSummary agrege les plus-values realisees d'une annee civile
+type YearSummary struct {
+	Year      int     `json:"year"`
+	Proceeds  float64 `json:"proceeds"`
+	CostBasis float64 `json:"costBasis"`
+	Gain      float64 `json:"gain"`
+}
+
+// Report contient le resultat complet du calcul
+type Report struct {
+	Method          string        `json:"method"`
+	Positions       []Position    `json:"positions"`
+	Realized        []Realization `json:"realized"`
+	Years           []YearSummary `json:"years"`
+	TotalRealized   float64       `json:"totalRealized"`
+	TotalUnrealized float64       `json:"totalUnrealized"`
+}
+
+// lot represente un achat non encore vendu (FIFO)
+type lot struct {
+	quantity float64
+	unitCost float64
+}
+
+// holding suit l'etat d'un titre pendant le calcul
+type holding struct {
+	quantity float64
+	cost     float64
+	lots     []lot
+}
+
+// Compute calcule les plus-values a partir des ordres (symboles dechiffres).
+// Les frais d'achat sont integres au prix de revient, les frais de vente
+// viennent en deduction du prix de cession.
+// quotes associe un symbole a son dernier cours pour les plus-values latentes.
+func Compute(trades []db.Trade, quotes map[string]float64, method string) (Report, error) {
+	if method != MethodFIFO {
+		method = MethodPRU
+	}
+
+	sorted := make([]db.Trade, len(trades))
+	copy(sorted, trades)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Date.Equal(sorted[j].Date) {
+			return sorted[i].ID < sorted[j].ID
+		}
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+
+	report := Report{Method: method, Positions: []Position{}, Realized: []Realization{}, Years: []YearSummary{}}
+	holdings := make(map[string]*holding)
+	var symbols []string
+
+	for _, t := range sorted {
+		h, ok := holdings[t.Symbol]
+		if !ok {
+			h = &holding{}
+			holdings[t.Symbol] = h
+			symbols = append(symbols, t.Symbol)
+		}
+
+		switch t.Side {
+		case "BUY":
+			cost := t.Quantity*t.Price + t.Fees
+			h.quantity += t.Quantity
+			h.cost += cost
+			if t.Quantity > 0 {
+				h.lots = append(h.lots, lot{quantity: t.Quantity, unitCost: cost / t.Quantity})
+			}
+
+		case "SELL":
+			if t.Quantity > h.quantity+quantityEpsilon {
+				return report, fmt.Errorf("%s le %s: %w", t.Symbol, t.Date.Format("2006-01-02"), ErrOversell)
+			}
+
+			var costOut float64
+			if method == MethodFIFO {
+				costOut = consumeLots(h, t.Quantity)
+			} else {
+				costOut = h.cost / h.quantity * t.Quantity
+			}
+
+			h.quantity -= t.Quantity
+			h.cost -= costOut
+			if h.quantity < quantityEpsilon {
+				h.quantity, h.cost, h.lots = 0, 0, nil
+			}
+
+			proceeds := t.Quantity*t.Price - t.Fees
+			report.Realized = append(report.Realized, Realization{
+				TradeID:   t.ID,
+				Symbol:    t.Symbol,
+				Date:      t.Date.Format("2006-01-02"),
+				Year:      t.Date.Year(),
+				Quantity:  t.Quantity,
+				Proceeds:  round2(proceeds),
+				CostBasis: round2(costOut),
+				Gain:      round2(proceeds - costOut),
+			})
+		}
+	}
+
+	// Agregation annuelle des plus-values realisees
+	yearIndex := make(map[int]int)
+	for _, r := range report.Realized {
+		idx, ok := yearIndex[r.Year]
+		if !ok {
+			idx = len(report.Years)
+			yearIndex[r.Year] = idx
+			report.Years = append(report.Years, YearSummary{Year: r.Year})
+		}
+		report.Years[idx].Proceeds = round2(report.Years[idx].Proceeds + r.Proceeds)
+		report.Years[idx].CostBasis = round2(report.Years[idx].CostBasis + r.CostBasis)
+		report.Years[idx].Gain = round2(report.Years[idx].Gain + r.Gain)
+		report.TotalRealized += r.Gain
+	}
+	report.TotalRealized = round2(report.TotalRealized)
+
+	// Positions restantes et plus-values latentes
+	sort.Strings(symbols)
+	for _, symbol := range symbols {
+		h := holdings[symbol]
+		if h.quantity == 0 {
+			continue
+		}
+		pos := Position{
+			Symbol:      symbol,
+			Quantity:    h.quantity,
+			CostBasis:   round2(h.cost),
+			AverageCost: round4(h.cost / h.quantity),
+		}
+		if price, ok := quotes[symbol]; ok {
+			value := round2(price * h.quantity)
+			unrealized := round2(value - h.cost)
+			pos.Price = &price
+			pos.MarketValue = &value
+			pos.Unrealized = &unrealized
+			report.TotalUnrealized += unrealized
+		}
+		report.Positions = append(report.Positions, pos)
+	}
+	report.TotalUnrealized = round2(report.TotalUnrealized)
+
+	return report, nil
+}
+
+// FilterYear restreint les plus-values realisees a une annee civile
+func (r Report) FilterYear(year int) Report {
+	realized := []Realization{}
+	for _, rz := range r.Realized {
+		if rz.Year == year {
+			realized = append(realized, rz)
+		}
+	}
+	years := []YearSummary{}
+	r.TotalRealized = 0
+	for _, y := range r.Years {
+		if y.Year == year {
+			years = append(years, y)
+			r.TotalRealized = y.Gain
+		}
+	}
+	r.Realized = realized
+	r.Years = years
+	return r
+}
+
+// consumeLots retire une quantite des lots les plus anciens et retourne leur cout
+func consumeLots(h *holding, quantity float64) float64 {
+	var cost float64
+	remaining := quantity
+	for remaining > quantityEpsilon && len(h.lots) > 0 {
+		l := &h.lots[0]
+		take := math.Min(l.quantity, remaining)
+		cost += take * l.unitCost
+		l.quantity -= take
+		remaining -= take
+		if l.quantity < quantityEpsilon {
+			h.lots = h.lots[1:]
+		}
+	}
+	return cost
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func round4(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
@@ -0,0 +1,103 @@
+package gains
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"pilot-finance/internal/db"
+)
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Deux achats a des prix differents puis une vente partielle
+var sampleTrades = []db.Trade{
+	{ID: 1, Symbol: "CW8", Side: "BUY", Quantity: 10, Price: 100, Fees: 0, Date: day(2023, 1, 10)},
+	{ID: 2, Symbol: "CW8", Side: "BUY", Quantity: 10, Price: 200, Fees: 0, Date: day(2023, 6, 10)},
+	{ID: 3, Symbol: "CW8", Side: "SELL", Quantity: 10, Price: 250, Fees: 0, Date: day(2024, 3, 1)},
+}
+
+func TestComputePRU(t *testing.T) {
+	report, err := Compute(sampleTrades, map[string]float64{"CW8": 300}, MethodPRU)
+	if err != nil {
+		t.Fatalf("Compute failed: %v", err)
+	}
+
+	// PRU = 150, vente de 10 a 250 => +1000
+	if report.TotalRealized != 1000 {
+		t.Errorf("TotalRealized = %v, want 1000", report.TotalRealized)
+	}
+	if len(report.Positions) != 1 || report.Positions[0].AverageCost != 150 {
+		t.Fatalf("Positions = %+v, want one position at PRU 150", report.Positions)
+	}
+	// Latent: 10 * (300 - 150) = 1500
+	if report.TotalUnrealized != 1500 {
+		t.Errorf("TotalUnrealized = %v, want 1500", report.TotalUnrealized)
+	}
+}
+
+func TestComputeFIFO(t *testing.T) {
+	report, err := Compute(sampleTrades, map[string]float64{"CW8": 300}, MethodFIFO)
+	if err != nil {
+		t.Fatalf("Compute failed: %v", err)
+	}
+
+	// Le premier lot (100) est vendu a 250 => +1500
+	if report.TotalRealized != 1500 {
+		t.Errorf("TotalRealized = %v, want 1500", report.TotalRealized)
+	}
+	// Reste le lot a 200 : 10 * (300 - 200) = 1000
+	if report.TotalUnrealized != 1000 {
+		t.Errorf("TotalUnrealized = %v, want 1000", report.TotalUnrealized)
+	}
+}
+
+func TestComputeFees(t *testing.T) {
+	trades := []db.Trade{
+		{ID: 1, Symbol: "AI", Side: "BUY", Quantity: 4, Price: 25, Fees: 2, Date: day(2024, 1, 5)},
+		{ID: 2, Symbol: "AI", Side: "SELL", Quantity: 4, Price: 30, Fees: 2, Date: day(2024, 2, 5)},
+	}
+	report, err := Compute(trades, nil, MethodPRU)
+	if err != nil {
+		t.Fatalf("Compute failed: %v", err)
+	}
+
+	// Cout 102, cession 118 => +16
+	if report.TotalRealized != 16 {
+		t.Errorf("TotalRealized = %v, want 16", report.TotalRealized)
+	}
+	if len(report.Positions) != 0 {
+		t.Errorf("Positions = %+v, want none", report.Positions)
+	}
+}
+
+func TestComputeOversell(t *testing.T) {
+	trades := []db.Trade{
+		{ID: 1, Symbol: "AI", Side: "BUY", Quantity: 1, Price: 10, Date: day(2024, 1, 5)},
+		{ID: 2, Symbol: "AI", Side: "SELL", Quantity: 2, Price: 10, Date: day(2024, 2, 5)},
+	}
+	if _, err := Compute(trades, nil, MethodPRU); !errors.Is(err, ErrOversell) {
+		t.Errorf("Compute error = %v, want ErrOversell", err)
+	}
+}
+
+func TestFilterYear(t *testing.T) {
+	trades := append([]db.Trade{}, sampleTrades...)
+	trades = append(trades, db.Trade{ID: 4, Symbol: "CW8", Side: "SELL", Quantity: 5, Price: 300, Date: day(2025, 2, 1)})
+
+	report, err := Compute(trades, nil, MethodPRU)
+	if err != nil {
+		t.Fatalf("Compute failed: %v", err)
+	}
+
+	filtered := report.FilterYear(2025)
+	if len(filtered.Realized) != 1 || filtered.Realized[0].TradeID != 4 {
+		t.Fatalf("Realized = %+v, want only trade 4", filtered.Realized)
+	}
+	// 5 * (300 - 150) = 750
+	if filtered.TotalRealized != 750 {
+		t.Errorf("TotalRealized = %v, want 750", filtered.TotalRealized)
+	}
+}
@@ -1,13 +1,17 @@
 package handlers
 
 import (
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
 	"pilot-finance/internal/crypto"
 	"pilot-finance/internal/db"
+	"pilot-finance/internal/ledger"
 	"pilot-finance/internal/middleware"
 	"pilot-finance/internal/projection"
 	"pilot-finance/internal/templates"
@@ -179,11 +183,19 @@ func DeleteAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Fichiers des pieces jointes du compte et de ses transactions
+	files, err := db.GetAttachmentsUnderAccount(id, user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
 	err = db.DeleteAccount(id, user.ID)
 	if err != nil {
 		http.Error(w, "Erreur suppression", http.StatusInternalServerError)
 		return
 	}
+	removeAttachmentFiles(files)
 
 	// Retourner la liste mise a jour en HTML
 	renderAccountsList(w, user.ID)
@@ -306,6 +318,7 @@ func renderAccountsList(w http.ResponseWriter, userID int64) {
 		}
 		accountMap[accounts[i].ID] = accounts[i].Name
 	}
+	decryptPockets(accounts)
 
 	// Calculer les yield payouts
 	yieldPayouts := projection.CalculateYieldPayouts(accounts, accountMap)
@@ -340,6 +353,7 @@ func renderAccountsList(w http.ResponseWriter, userID int64) {
 			"YieldRate":     payout.Rate,
 		})
 	}
+	allocations := allocationData(userID, accountMap)
 	for _, rec := range recurrings {
 		description := rec.Description
 		if decrypted, err := crypto.Decrypt(rec.Description); err == nil {
@@ -360,6 +374,8 @@ func renderAccountsList(w http.ResponseWriter, userID int64) {
 			"ToAccountName": toAccountName,
 			"IsActive":      rec.IsActive,
 			"IsYieldPayout": false,
+			"Category":      rec.Category,
+			"Allocations":   allocations[rec.ID],
 		})
 	}
 
@@ -386,3 +402,423 @@ func renderAccountsList(w http.ResponseWriter, userID int64) {
 	})
 	w.Write([]byte(`</div>`))
 }
+
+// requireAccount charge le compte {id} de l'URL en verifiant qu'il appartient a l'utilisateur.
+// En cas d'echec la reponse d'erreur est deja ecrite et nil est retourne.
+func requireAccount(w http.ResponseWriter, r *http.Request, userID int64) *db.Account {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID invalide", http.StatusBadRequest)
+		return nil
+	}
+
+	acc, err := db.GetAccountByID(id, userID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return nil
+	}
+	if acc == nil {
+		http.Error(w, "Compte non trouve", http.StatusNotFound)
+		return nil
+	}
+
+	if decrypted, err := crypto.Decrypt(acc.Name); err == nil {
+		acc.Name = decrypted
+	}
+	return acc
+}
+
+// UpdateValuation configure un compte comme bien (immobilier, vehicule) avec sa regle de valorisation
+func UpdateValuation(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	acc := requireAccount(w, r, user.ID)
+	if acc == nil {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	rule := r.FormValue("valuationRule")
+
+	// Sans regle, un bien redevient un compte standard
+	if rule == "" {
+		if err := db.UpdateAccountValuation(acc.ID, user.ID, resetKind(acc, "ASSET"), 0, nil, "", 0, 0); err != nil {
+			http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+			return
+		}
+		renderAccountsList(w, user.ID)
+		return
+	}
+
+	if rule != "ANNUAL_RATE" && rule != "LINEAR" {
+		http.Error(w, "Regle de valorisation invalide", http.StatusBadRequest)
+		return
+	}
+
+	purchasePrice, _ := strconv.ParseFloat(r.FormValue("purchasePrice"), 64)
+	if purchasePrice < 0 {
+		http.Error(w, "Prix d'achat invalide", http.StatusBadRequest)
+		return
+	}
+
+	var purchaseDate *time.Time
+	if d := r.FormValue("purchaseDate"); d != "" {
+		t, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			http.Error(w, "Date d'achat invalide", http.StatusBadRequest)
+			return
+		}
+		purchaseDate = &t
+	}
+
+	rate, _ := strconv.ParseFloat(r.FormValue("valuationRate"), 64)
+	years, _ := strconv.Atoi(r.FormValue("depreciationYears"))
+	if rule == "LINEAR" && years <= 0 {
+		http.Error(w, "Duree d'amortissement requise", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.UpdateAccountValuation(acc.ID, user.ID, "ASSET", purchasePrice, purchaseDate, rule, rate, years); err != nil {
+		http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+		return
+	}
+
+	// Optionnel: aligner le solde sur la valeur theorique du jour
+	if r.FormValue("syncBalance") == "on" || r.FormValue("syncBalance") == "true" {
+		acc.Kind, acc.PurchasePrice, acc.PurchaseDate = "ASSET", purchasePrice, purchaseDate
+		acc.ValuationRule, acc.ValuationRate, acc.DepreciationYears = rule, rate, years
+		if value, ok := projection.AssetValueAt(*acc, time.Now()); ok {
+			if err := db.UpdateAccountBalance(acc.ID, user.ID, math.Round(value*100)/100); err != nil {
+				http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	renderAccountsList(w, user.ID)
+}
+
+// UpdateFees met a jour les frais de gestion d'un compte (assurance-vie, fonds)
+func UpdateFees(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	acc := requireAccount(w, r, user.ID)
+	if acc == nil {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	// Champs vides = pas de frais
+	parseFee := func(name string) (float64, bool) {
+		v := r.FormValue(name)
+		if v == "" {
+			return 0, true
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil && f >= 0
+	}
+
+	annualRate, ok1 := parseFee("feeAnnualRate")
+	depositRate, ok2 := parseFee("feeDepositRate")
+	fixedYearly, ok3 := parseFee("feeFixedYearly")
+	if !ok1 || !ok2 || !ok3 || annualRate > 100 || depositRate > 100 {
+		http.Error(w, "Frais invalides", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.UpdateAccountFees(acc.ID, user.ID, annualRate, depositRate, fixedYearly); err != nil {
+		http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+		return
+	}
+
+	renderAccountsList(w, user.ID)
+}
+
+// UpdateRateSchedule remplace le bareme de taux d'un compte.
+// Le formulaire contient des listes paralleles effectiveDate[], tierMin[] et rate[] ;
+// une liste vide supprime le bareme (retour au taux YieldMin/YieldMax).
+func UpdateRateSchedule(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	acc := requireAccount(w, r, user.ID)
+	if acc == nil {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	dates := r.Form["effectiveDate"]
+	tierMins := r.Form["tierMin"]
+	rates := r.Form["rate"]
+	if len(dates) != len(rates) || (len(tierMins) != 0 && len(tierMins) != len(rates)) {
+		http.Error(w, "Bareme incomplet", http.StatusBadRequest)
+		return
+	}
+
+	segments := make([]db.RateSegment, 0, len(rates))
+	for i := range rates {
+		date, err := time.Parse("2006-01-02", dates[i])
+		if err != nil {
+			http.Error(w, "Date d'effet invalide", http.StatusBadRequest)
+			return
+		}
+		rate, err := strconv.ParseFloat(rates[i], 64)
+		if err != nil {
+			http.Error(w, "Taux invalide", http.StatusBadRequest)
+			return
+		}
+		tierMin := 0.0
+		if len(tierMins) > 0 && tierMins[i] != "" {
+			tierMin, err = strconv.ParseFloat(tierMins[i], 64)
+			if err != nil || tierMin < 0 {
+				http.Error(w, "Seuil de tranche invalide", http.StatusBadRequest)
+				return
+			}
+		}
+		segments = append(segments, db.RateSegment{EffectiveDate: date, TierMin: tierMin, Rate: rate})
+	}
+
+	if err := db.ReplaceRateSchedule(acc.ID, user.ID, segments); err != nil {
+		http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+		return
+	}
+
+	renderAccountsList(w, user.ID)
+}
+
+// UpdatePockets remplace la repartition d'un compte en poches virtuelles.
+// Le formulaire contient des listes paralleles name[], amount[], goal[] et color[] ;
+// les poches doivent totaliser le solde du compte, une liste vide les supprime.
+func UpdatePockets(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	acc := requireAccount(w, r, user.ID)
+	if acc == nil {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	names := r.Form["name"]
+	amounts := r.Form["amount"]
+	goals := r.Form["goal"]
+	colors := r.Form["color"]
+	if len(names) != len(amounts) || (len(goals) != 0 && len(goals) != len(amounts)) ||
+		(len(colors) != 0 && len(colors) != len(amounts)) {
+		http.Error(w, "Repartition incomplete", http.StatusBadRequest)
+		return
+	}
+
+	pockets := make([]db.Pocket, 0, len(amounts))
+	for i := range amounts {
+		name := strings.TrimSpace(names[i])
+		if name == "" {
+			http.Error(w, "Nom de poche requis", http.StatusBadRequest)
+			return
+		}
+		amount, err := strconv.ParseFloat(amounts[i], 64)
+		if err != nil || amount < 0 {
+			http.Error(w, "Montant invalide", http.StatusBadRequest)
+			return
+		}
+		pocket := db.Pocket{Amount: amount}
+		if len(goals) > 0 && goals[i] != "" {
+			goal, err := strconv.ParseFloat(goals[i], 64)
+			if err != nil || goal < 0 {
+				http.Error(w, "Objectif invalide", http.StatusBadRequest)
+				return
+			}
+			pocket.Goal = &goal
+		}
+		if len(colors) > 0 {
+			pocket.Color = colors[i]
+		}
+		if pocket.Name, err = crypto.Encrypt(name); err != nil {
+			http.Error(w, "Erreur chiffrement", http.StatusInternalServerError)
+			return
+		}
+		pockets = append(pockets, pocket)
+	}
+
+	if len(pockets) > 0 && !ledger.ValidatePockets(acc.Balance, pockets) {
+		http.Error(w, "Les poches doivent totaliser le solde du compte", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.ReplacePockets(acc.ID, user.ID, pockets); err != nil {
+		http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+		return
+	}
+
+	renderAccountsList(w, user.ID)
+}
+
+// resetKind retourne le type d'un compte dont on retire le parametrage propre a kind :
+// seul un compte de ce type redevient standard, les autres gardent le leur
+func resetKind(acc *db.Account, kind string) string {
+	if acc.Kind == kind {
+		return "STANDARD"
+	}
+	return acc.Kind
+}
+
+// decryptPockets dechiffre les noms des poches des comptes
+func decryptPockets(accounts []db.Account) {
+	for i := range accounts {
+		for j := range accounts[i].Pockets {
+			if decrypted, err := crypto.Decrypt(accounts[i].Pockets[j].Name); err == nil {
+				accounts[i].Pockets[j].Name = decrypted
+			}
+		}
+	}
+}
+
+// UpdateTerm configure un compte a terme (taux fixe, dates et renouvellement a l'echeance)
+func UpdateTerm(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	acc := requireAccount(w, r, user.ID)
+	if acc == nil {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	// Sans date d'echeance, un compte a terme redevient un compte standard
+	if r.FormValue("maturityDate") == "" {
+		if err := db.UpdateAccountTerm(acc.ID, user.ID, resetKind(acc, "TERM_DEPOSIT"), 0, nil, nil, "", nil); err != nil {
+			http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+			return
+		}
+		renderAccountsList(w, user.ID)
+		return
+	}
+
+	rate, err := strconv.ParseFloat(r.FormValue("termRate"), 64)
+	if err != nil || rate < 0 {
+		http.Error(w, "Taux invalide", http.StatusBadRequest)
+		return
+	}
+
+	startDate, err := time.Parse("2006-01-02", r.FormValue("startDate"))
+	if err != nil {
+		http.Error(w, "Date de debut invalide", http.StatusBadRequest)
+		return
+	}
+	maturityDate, err := time.Parse("2006-01-02", r.FormValue("maturityDate"))
+	if err != nil || !maturityDate.After(startDate) {
+		http.Error(w, "Date d'echeance invalide", http.StatusBadRequest)
+		return
+	}
+
+	rollover := r.FormValue("rollover")
+	if rollover != "ROLLOVER" && rollover != "TRANSFER" {
+		http.Error(w, "Politique d'echeance invalide", http.StatusBadRequest)
+		return
+	}
+
+	var targetAccountID *int64
+	if rollover == "TRANSFER" {
+		targetID, err := strconv.ParseInt(r.FormValue("targetAccountId"), 10, 64)
+		if err != nil || targetID == acc.ID {
+			http.Error(w, "Compte destinataire invalide", http.StatusBadRequest)
+			return
+		}
+		if target, err := db.GetAccountByID(targetID, user.ID); err != nil || target == nil {
+			http.Error(w, "Compte destinataire invalide", http.StatusBadRequest)
+			return
+		}
+		targetAccountID = &targetID
+	}
+
+	if err := db.UpdateAccountTerm(acc.ID, user.ID, "TERM_DEPOSIT", rate, &startDate, &maturityDate, rollover, targetAccountID); err != nil {
+		http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+		return
+	}
+
+	renderAccountsList(w, user.ID)
+}
+
+// UpdateDebt configure un compte de dette (taux annuel et mensualite minimale).
+// Sans mensualite, le compte redevient un compte standard.
+func UpdateDebt(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	acc := requireAccount(w, r, user.ID)
+	if acc == nil {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	if r.FormValue("minPayment") == "" {
+		if err := db.UpdateAccountDebt(acc.ID, user.ID, resetKind(acc, "LIABILITY"), 0, 0); err != nil {
+			http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+			return
+		}
+		renderAccountsList(w, user.ID)
+		return
+	}
+
+	rate, err := strconv.ParseFloat(r.FormValue("debtRate"), 64)
+	if err != nil || rate < 0 {
+		http.Error(w, "Taux invalide", http.StatusBadRequest)
+		return
+	}
+	minPayment, err := strconv.ParseFloat(r.FormValue("minPayment"), 64)
+	if err != nil || minPayment <= 0 {
+		http.Error(w, "Mensualite invalide", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.UpdateAccountDebt(acc.ID, user.ID, "LIABILITY", rate, minPayment); err != nil {
+		http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+		return
+	}
+
+	renderAccountsList(w, user.ID)
+}
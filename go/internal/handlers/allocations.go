@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"pilot-finance/internal/db"
+	"pilot-finance/internal/ledger"
+	"pilot-finance/internal/middleware"
+)
+
+// AllocationRulesAPI retourne les regles de repartition des revenus en JSON
+func AllocationRulesAPI(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	rules, err := db.GetAllocationRulesByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	if rules == nil {
+		rules = []db.AllocationRule{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rules)
+}
+
+// UpdateAllocationRules remplace la repartition d'un revenu recurrent : chaque regle
+// vire un pourcentage du revenu vers un compte, le reste demeure sur le compte credite
+func UpdateAllocationRules(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID invalide", http.StatusBadRequest)
+		return
+	}
+
+	recurrings, err := db.GetRecurringByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	var rec *db.RecurringOperation
+	for i := range recurrings {
+		if recurrings[i].ID == id {
+			rec = &recurrings[i]
+		}
+	}
+	if rec == nil {
+		http.Error(w, "Operation non trouvee", http.StatusNotFound)
+		return
+	}
+	if rec.ToAccountID != nil || rec.Amount <= 0 {
+		http.Error(w, "Seul un revenu peut etre reparti", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	accountIDs := r.Form["toAccountId"]
+	percents := r.Form["percent"]
+	if len(accountIDs) != len(percents) {
+		http.Error(w, "Repartition incomplete", http.StatusBadRequest)
+		return
+	}
+
+	rules := make([]db.AllocationRule, 0, len(percents))
+	for i := range percents {
+		toAccountID, err := strconv.ParseInt(accountIDs[i], 10, 64)
+		if err != nil || toAccountID == rec.AccountID {
+			http.Error(w, "Compte destination invalide", http.StatusBadRequest)
+			return
+		}
+		acc, err := db.GetAccountByID(toAccountID, user.ID)
+		if err != nil {
+			http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+			return
+		}
+		if acc == nil {
+			http.Error(w, "Compte non trouve", http.StatusNotFound)
+			return
+		}
+		percent, err := strconv.ParseFloat(percents[i], 64)
+		if err != nil {
+			http.Error(w, "Pourcentage invalide", http.StatusBadRequest)
+			return
+		}
+		rules = append(rules, db.AllocationRule{ToAccountID: toAccountID, Percent: percent})
+	}
+
+	if !ledger.ValidateAllocations(rules) {
+		http.Error(w, "Les pourcentages doivent etre positifs et totaliser au plus 100%", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.ReplaceAllocationRules(rec.ID, user.ID, rules); err != nil {
+		http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+		return
+	}
+
+	renderRecurringTable(w, user.ID)
+}
+
+// allocationData prepare l'affichage des regles de repartition, par operation de revenu
+func allocationData(userID int64, accountMap map[int64]string) map[int64][]map[string]interface{} {
+	rules, _ := db.GetAllocationRulesByUserID(userID)
+	data := make(map[int64][]map[string]interface{})
+	for _, rule := range rules {
+		data[rule.RecurringID] = append(data[rule.RecurringID], map[string]interface{}{
+			"ToAccountID":   rule.ToAccountID,
+			"ToAccountName": accountMap[rule.ToAccountID],
+			"Percent":       rule.Percent,
+		})
+	}
+	return data
+}
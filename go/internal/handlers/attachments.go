@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"pilot-finance/internal/attachments"
+	"pilot-finance/internal/crypto"
+	"pilot-finance/internal/db"
+	"pilot-finance/internal/middleware"
+)
+
+// readAttachment lit le fichier envoye et verifie sa taille et son type
+func readAttachment(w http.ResponseWriter, r *http.Request) ([]byte, string, string, bool) {
+	r.Body = http.MaxBytesReader(w, r.Body, attachments.MaxSize+1<<20)
+	if err := r.ParseMultipartForm(attachments.MaxSize); err != nil {
+		http.Error(w, attachments.ErrTooLarge.Error(), http.StatusRequestEntityTooLarge)
+		return nil, "", "", false
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Fichier requis", http.StatusBadRequest)
+		return nil, "", "", false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, attachments.MaxSize+1))
+	if err != nil {
+		http.Error(w, "Lecture du fichier impossible", http.StatusBadRequest)
+		return nil, "", "", false
+	}
+
+	contentType, err := attachments.DetectType(data)
+	if err != nil {
+		status := http.StatusUnsupportedMediaType
+		if errors.Is(err, attachments.ErrTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, err.Error(), status)
+		return nil, "", "", false
+	}
+
+	return data, header.Filename, contentType, true
+}
+
+// storeAttachment chiffre le fichier sur disque et l'enregistre en base
+func storeAttachment(w http.ResponseWriter, r *http.Request, a db.Attachment) {
+	data, filename, contentType, ok := readAttachment(w, r)
+	if !ok {
+		return
+	}
+
+	encryptedName, err := crypto.Encrypt(filename)
+	if err != nil {
+		http.Error(w, "Erreur chiffrement", http.StatusInternalServerError)
+		return
+	}
+
+	storageName, err := attachments.Save(data)
+	if err != nil {
+		http.Error(w, "Erreur enregistrement", http.StatusInternalServerError)
+		return
+	}
+
+	a.Filename = encryptedName
+	a.ContentType = contentType
+	a.Size = int64(len(data))
+	a.StorageName = storageName
+	id, err := db.CreateAttachment(a)
+	if err != nil {
+		attachments.Remove(storageName)
+		http.Error(w, "Erreur creation", http.StatusInternalServerError)
+		return
+	}
+
+	a.ID = id
+	a.Filename = filename
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a)
+}
+
+// UploadTransactionAttachment joint un fichier a une transaction
+func UploadTransactionAttachment(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID invalide", http.StatusBadRequest)
+		return
+	}
+	t, err := db.GetTransactionByID(id, user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	if t == nil {
+		http.Error(w, "Transaction non trouvee", http.StatusNotFound)
+		return
+	}
+
+	storeAttachment(w, r, db.Attachment{UserID: user.ID, TransactionID: &t.ID})
+}
+
+// UploadAccountAttachment joint un fichier (contrat, releve) a un compte
+func UploadAccountAttachment(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	acc := requireAccount(w, r, user.ID)
+	if acc == nil {
+		return
+	}
+
+	storeAttachment(w, r, db.Attachment{UserID: user.ID, AccountID: &acc.ID})
+}
+
+// AttachmentsAPI liste les pieces jointes d'une transaction ou d'un compte
+func AttachmentsAPI(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	var list []db.Attachment
+	var err error
+	q := r.URL.Query()
+	switch {
+	case q.Get("transactionId") != "":
+		id, perr := strconv.ParseInt(q.Get("transactionId"), 10, 64)
+		if perr != nil {
+			http.Error(w, "ID invalide", http.StatusBadRequest)
+			return
+		}
+		list, err = db.GetAttachmentsByTransaction(id, user.ID)
+	case q.Get("accountId") != "":
+		id, perr := strconv.ParseInt(q.Get("accountId"), 10, 64)
+		if perr != nil {
+			http.Error(w, "ID invalide", http.StatusBadRequest)
+			return
+		}
+		list, err = db.GetAttachmentsByAccount(id, user.ID)
+	default:
+		http.Error(w, "transactionId ou accountId requis", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range list {
+		if decrypted, err := crypto.Decrypt(list[i].Filename); err == nil {
+			list[i].Filename = decrypted
+		}
+	}
+	if list == nil {
+		list = []db.Attachment{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// DownloadAttachment dechiffre et renvoie une piece jointe a son proprietaire
+func DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID invalide", http.StatusBadRequest)
+		return
+	}
+
+	a, err := db.GetAttachmentByID(id, user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	if a == nil {
+		http.Error(w, "Piece jointe non trouvee", http.StatusNotFound)
+		return
+	}
+
+	data, err := attachments.Load(a.StorageName)
+	if err != nil {
+		http.Error(w, "Erreur lecture", http.StatusInternalServerError)
+		return
+	}
+
+	filename := a.Filename
+	if decrypted, err := crypto.Decrypt(a.Filename); err == nil {
+		filename = decrypted
+	}
+	filename = strings.Map(func(c rune) rune {
+		if c == '"' || c == '/' || c == '\\' || c < ' ' {
+			return '_'
+		}
+		return c
+	}, filename)
+
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Write(data)
+}
+
+// DeleteAttachment supprime une piece jointe et son fichier
+func DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID invalide", http.StatusBadRequest)
+		return
+	}
+
+	a, err := db.GetAttachmentByID(id, user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	if a == nil {
+		http.Error(w, "Piece jointe non trouvee", http.StatusNotFound)
+		return
+	}
+
+	if err := db.DeleteAttachment(id, user.ID); err != nil {
+		http.Error(w, "Erreur suppression", http.StatusInternalServerError)
+		return
+	}
+	removeAttachmentFiles([]db.Attachment{*a})
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// removeAttachmentFiles supprime les fichiers des pieces jointes dont les lignes
+// ont ete supprimees (directement ou en cascade avec leur transaction ou compte)
+func removeAttachmentFiles(list []db.Attachment) {
+	for _, a := range list {
+		if err := attachments.Remove(a.StorageName); err != nil {
+			log.Printf("Suppression piece jointe %d: %v", a.ID, err)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"pilot-finance/internal/budget"
+	"pilot-finance/internal/db"
+	"pilot-finance/internal/middleware"
+)
+
+// BudgetsAPI retourne les budgets de categorie
+func BudgetsAPI(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	budgets, err := db.GetBudgetsByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	if budgets == nil {
+		budgets = []db.Budget{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budgets)
+}
+
+// SaveBudget cree ou met a jour le budget mensuel d'une categorie
+func SaveBudget(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	b := db.Budget{
+		UserID:   user.ID,
+		Category: strings.TrimSpace(r.FormValue("category")),
+		Rollover: r.FormValue("rollover") == "true" || r.FormValue("rollover") == "on",
+	}
+	if b.Category == "" {
+		http.Error(w, "Categorie requise", http.StatusBadRequest)
+		return
+	}
+
+	amount, err := strconv.ParseFloat(r.FormValue("amount"), 64)
+	if err != nil || amount < 0 {
+		http.Error(w, "Montant invalide", http.StatusBadRequest)
+		return
+	}
+	b.Amount = amount
+
+	// Le mois de depart d'un budget existant est conserve sauf s'il est fourni
+	b.StartMonth = budget.MonthStart(time.Now())
+	if v := r.FormValue("startMonth"); v != "" {
+		start, err := budget.ParseMonth(v)
+		if err != nil {
+			http.Error(w, "Mois invalide", http.StatusBadRequest)
+			return
+		}
+		b.StartMonth = start
+	} else {
+		existing, err := db.GetBudgetsByUserID(user.ID)
+		if err != nil {
+			http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+			return
+		}
+		for _, e := range existing {
+			if e.Category == b.Category {
+				b.StartMonth = e.StartMonth
+			}
+		}
+	}
+
+	if err := db.SaveBudget(b); err != nil {
+		http.Error(w, "Erreur enregistrement", http.StatusInternalServerError)
+		return
+	}
+
+	BudgetsAPI(w, r)
+}
+
+// DeleteBudget supprime un budget de categorie
+func DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID invalide", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DeleteBudget(id, user.ID); err != nil {
+		http.Error(w, "Erreur suppression", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// BudgetReportAPI retourne le budget face au reel pour un mois (YYYY-MM, par defaut le mois en cours)
+func BudgetReportAPI(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+	if !checkBudgetMode(w, user.ID, budget.ModeCategory) {
+		return
+	}
+
+	now := time.Now()
+	month := budget.MonthStart(now)
+	if v := r.URL.Query().Get("month"); v != "" {
+		m, err := budget.ParseMonth(v)
+		if err != nil {
+			http.Error(w, "Mois invalide", http.StatusBadRequest)
+			return
+		}
+		month = m
+	}
+
+	budgets, err := db.GetBudgetsByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	txs, err := db.GetTransactionsByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	recurrings, err := db.GetRecurringByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budget.Compute(budgets, txs, recurrings, month, now))
+}
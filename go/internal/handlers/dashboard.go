@@ -4,9 +4,11 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"pilot-finance/internal/crypto"
 	"pilot-finance/internal/db"
+	"pilot-finance/internal/ledger"
 	"pilot-finance/internal/middleware"
 	"pilot-finance/internal/projection"
 	"pilot-finance/internal/templates"
@@ -41,25 +43,17 @@ func DashboardAPI(w http.ResponseWriter, r *http.Request) {
 			accounts[i].Name = decrypted
 		}
 	}
+	decryptPockets(accounts)
 
 	// Calculer les projections
-	data := projection.Calculate(accounts, years)
+	data := projection.CalculateWithOptions(accounts, years, projectionOptions(user.ID))
 
 	// Recuperer les operations recurrentes pour le resume mensuel
 	recurrings, _ := db.GetRecurringByUserID(user.ID)
 	summary := projection.CalculateMonthlySummary(recurrings, accounts)
 
 	// Preparer les donnees pour les graphiques
-	pieData := make([]map[string]interface{}, 0)
-	for _, acc := range accounts {
-		if acc.Balance > 0 {
-			pieData = append(pieData, map[string]interface{}{
-				"name":  acc.Name,
-				"value": acc.Balance,
-				"color": acc.Color,
-			})
-		}
-	}
+	pieData := pieSlices(accounts)
 
 	// Preparer les donnees de projection pour le graphique
 	projectionData := make([]map[string]interface{}, len(data.Projection))
@@ -71,6 +65,7 @@ func DashboardAPI(w http.ResponseWriter, r *http.Request) {
 			"totalMin": p.TotalMin,
 			"totalMax": p.TotalMax,
 			"accounts": p.Accounts,
+			"events":   p.Events,
 		}
 	}
 
@@ -87,11 +82,14 @@ func DashboardAPI(w http.ResponseWriter, r *http.Request) {
 		"accounts":        accounts,
 		"totalBalance":    data.TotalBalance,
 		"totalInterests":  data.TotalInterests,
+		"totalFees":       data.TotalFees,
+		"feesByAccount":   data.FeesByAccount,
 		"projectionTotal": data.Projection[len(data.Projection)-1].TotalAvg,
 		"projection":      projectionData,
 		"pieData":         pieData,
 		"years":           years,
 		"monthly":         summary,
+		"maturities":      projection.UpcomingMaturities(accounts, time.Now(), 12),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -126,19 +124,11 @@ func DashboardPartial(w http.ResponseWriter, r *http.Request) {
 			accounts[i].Name = decrypted
 		}
 	}
+	decryptPockets(accounts)
 
-	data := projection.Calculate(accounts, years)
+	data := projection.CalculateWithOptions(accounts, years, projectionOptions(user.ID))
 
-	pieData := make([]map[string]interface{}, 0)
-	for _, acc := range accounts {
-		if acc.Balance > 0 {
-			pieData = append(pieData, map[string]interface{}{
-				"name":  acc.Name,
-				"value": acc.Balance,
-				"color": acc.Color,
-			})
-		}
-	}
+	pieData := pieSlices(accounts)
 
 	projectionData := make([]map[string]interface{}, len(data.Projection))
 	for i, p := range data.Projection {
@@ -149,6 +139,7 @@ func DashboardPartial(w http.ResponseWriter, r *http.Request) {
 			"totalMin": p.TotalMin,
 			"totalMax": p.TotalMax,
 			"accounts": p.Accounts,
+			"events":   p.Events,
 		}
 	}
 
@@ -166,6 +157,7 @@ func DashboardPartial(w http.ResponseWriter, r *http.Request) {
 		"AccountColors":   accountColors,
 		"TotalBalance":    data.TotalBalance,
 		"TotalInterests":  data.TotalInterests,
+		"TotalFees":       data.TotalFees,
 		"ProjectionTotal": data.Projection[len(data.Projection)-1].TotalAvg,
 		"ProjectionData":  projectionData,
 		"PieData":         pieData,
@@ -205,6 +197,7 @@ func AccountsAPI(w http.ResponseWriter, r *http.Request) {
 			accounts[i].Name = decrypted
 		}
 	}
+	decryptPockets(accounts)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(accounts)
@@ -252,6 +245,7 @@ func RecurringAPI(w http.ResponseWriter, r *http.Request) {
 			"toAccountId":   rec.ToAccountID,
 			"toAccountName": "",
 			"isActive":      rec.IsActive,
+			"category":      rec.Category,
 		}
 
 		if rec.ToAccountID != nil {
@@ -262,3 +256,98 @@ func RecurringAPI(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
 }
+
+// pieSlices prepare les parts du camembert : un compte reparti en poches
+// apparait poche par poche, avec le reste non reparti le cas echeant. Si les poches
+// depassent le solde, elles sont ramenees au solde et le reste negatif n'apparait
+// que dans la legende.
+func pieSlices(accounts []db.Account) []map[string]interface{} {
+	pieData := make([]map[string]interface{}, 0)
+	slice := func(name string, value float64, color string) {
+		if value > 0 {
+			pieData = append(pieData, map[string]interface{}{
+				"name":  name,
+				"value": value,
+				"color": color,
+			})
+		}
+	}
+
+	for _, acc := range accounts {
+		if len(acc.Pockets) == 0 {
+			slice(acc.Name, acc.Balance, acc.Color)
+			continue
+		}
+		for _, p := range ledger.ScalePockets(acc.Balance, acc.Pockets) {
+			color := p.Color
+			if color == "" {
+				color = acc.Color
+			}
+			slice(acc.Name+" · "+p.Name, p.Amount, color)
+		}
+		rest := ledger.Unallocated(acc.Balance, acc.Pockets)
+		if rest < 0 {
+			pieData = append(pieData, map[string]interface{}{
+				"name":  acc.Name + " · Non reparti",
+				"value": rest,
+				"color": acc.Color,
+			})
+			continue
+		}
+		slice(acc.Name+" · Non reparti", rest, acc.Color)
+	}
+	return pieData
+}
+
+// projectionOptions charge les donnees optionnelles de la simulation (dechiffrees)
+func projectionOptions(userID int64) projection.Options {
+	events, _ := db.GetPlannedEventsByUserID(userID)
+	for i := range events {
+		if decrypted, err := crypto.Decrypt(events[i].Description); err == nil {
+			events[i].Description = decrypted
+		}
+	}
+	recurrings, _ := db.GetRecurringByUserID(userID)
+	allocations, _ := db.GetAllocationRulesByUserID(userID)
+	return projection.Options{Events: events, Recurrings: recurrings, Allocations: allocations}
+}
+
+// ForecastAPI retourne la prevision de tresorerie jour par jour (days, 90 par defaut)
+// avec les periodes de decouvert des comptes courants
+func ForecastAPI(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	days := 90
+	if d := r.URL.Query().Get("days"); d != "" {
+		if parsed, err := strconv.Atoi(d); err == nil && parsed >= 1 && parsed <= 366 {
+			days = parsed
+		}
+	}
+
+	accounts, err := db.GetAccountsByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	for i := range accounts {
+		if decrypted, err := crypto.Decrypt(accounts[i].Name); err == nil {
+			accounts[i].Name = decrypted
+		}
+	}
+
+	opts := projectionOptions(user.ID)
+	for i := range opts.Recurrings {
+		if decrypted, err := crypto.Decrypt(opts.Recurrings[i].Description); err == nil {
+			opts.Recurrings[i].Description = decrypted
+		}
+	}
+
+	forecast := projection.Forecast(accounts, opts, time.Now(), days)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(forecast)
+}
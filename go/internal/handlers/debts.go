@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"pilot-finance/internal/crypto"
+	"pilot-finance/internal/db"
+	"pilot-finance/internal/middleware"
+	"pilot-finance/internal/projection"
+)
+
+// DebtPayoffAPI compare les strategies de remboursement des comptes de dette.
+// extra est le montant mensuel ajoute aux mensualites minimales ; order (IDs separes
+// par des virgules) ajoute un plan dans l'ordre choisi.
+func DebtPayoffAPI(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	var extra float64
+	if v := r.URL.Query().Get("extra"); v != "" {
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil || parsed < 0 {
+			http.Error(w, "Montant invalide", http.StatusBadRequest)
+			return
+		}
+		extra = parsed
+	}
+
+	var custom []int64
+	if v := r.URL.Query().Get("order"); v != "" {
+		for _, part := range strings.Split(v, ",") {
+			id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+			if err != nil {
+				http.Error(w, "Ordre invalide", http.StatusBadRequest)
+				return
+			}
+			custom = append(custom, id)
+		}
+	}
+
+	accounts, err := db.GetAccountsByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	for i := range accounts {
+		if decrypted, err := crypto.Decrypt(accounts[i].Name); err == nil {
+			accounts[i].Name = decrypted
+		}
+	}
+
+	debts := projection.DebtsFromAccounts(accounts)
+	if debts == nil {
+		debts = []projection.Debt{}
+	}
+
+	strategies := []string{projection.StrategyAvalanche, projection.StrategySnowball}
+	if len(custom) > 0 {
+		strategies = append(strategies, projection.StrategyCustom)
+	}
+
+	plans := make([]projection.PayoffPlan, 0, len(strategies))
+	for _, strategy := range strategies {
+		plan, err := projection.PlanPayoff(debts, extra, strategy, custom, time.Now())
+		if err == projection.ErrPayoffNotReached {
+			http.Error(w, "Les mensualites ne couvrent pas les interets", http.StatusUnprocessableEntity)
+			return
+		}
+		plans = append(plans, plan)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"debts": debts,
+		"extra": extra,
+		"plans": plans,
+	})
+}
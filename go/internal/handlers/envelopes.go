@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"pilot-finance/internal/budget"
+	"pilot-finance/internal/crypto"
+	"pilot-finance/internal/db"
+	"pilot-finance/internal/middleware"
+)
+
+// BudgetModeAPI retourne le mode de budget de l'utilisateur
+func BudgetModeAPI(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	mode, err := db.GetBudgetMode(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"mode": mode})
+}
+
+// SetBudgetMode choisit entre budgets par categorie et enveloppes
+func SetBudgetMode(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	mode := r.FormValue("mode")
+	if mode != budget.ModeCategory && mode != budget.ModeEnvelope {
+		http.Error(w, "Mode invalide", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.SetBudgetMode(user.ID, mode); err != nil {
+		http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+		return
+	}
+
+	BudgetModeAPI(w, r)
+}
+
+// checkBudgetMode refuse un rapport qui ne correspond pas au mode de budget choisi
+func checkBudgetMode(w http.ResponseWriter, userID int64, mode string) bool {
+	current, err := db.GetBudgetMode(userID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return false
+	}
+	if current != mode {
+		http.Error(w, "Mode de budget inactif", http.StatusConflict)
+		return false
+	}
+	return true
+}
+
+// loadEnvelopes récupère les enveloppes avec leurs noms dechiffres
+func loadEnvelopes(userID int64) ([]db.Envelope, error) {
+	envelopes, err := db.GetEnvelopesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range envelopes {
+		if decrypted, err := crypto.Decrypt(envelopes[i].Name); err == nil {
+			envelopes[i].Name = decrypted
+		}
+	}
+	return envelopes, nil
+}
+
+// findEnvelope retourne l'enveloppe de l'utilisateur portant cet ID
+func findEnvelope(envelopes []db.Envelope, id int64) *db.Envelope {
+	for i := range envelopes {
+		if envelopes[i].ID == id {
+			return &envelopes[i]
+		}
+	}
+	return nil
+}
+
+// envelopeMonth lit le mois vise (YYYY-MM), par defaut le mois en cours
+func envelopeMonth(value string) (time.Time, bool) {
+	if value == "" {
+		return budget.MonthStart(time.Now()), true
+	}
+	month, err := budget.ParseMonth(value)
+	return month, err == nil
+}
+
+// EnvelopesAPI retourne les enveloppes
+func EnvelopesAPI(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	envelopes, err := loadEnvelopes(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	if envelopes == nil {
+		envelopes = []db.Envelope{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(envelopes)
+}
+
+// SaveEnvelope cree ou met a jour une enveloppe
+func SaveEnvelope(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	e := db.Envelope{
+		UserID:   user.ID,
+		Category: strings.TrimSpace(r.FormValue("category")),
+	}
+	if e.Category == "" {
+		http.Error(w, "Categorie requise", http.StatusBadRequest)
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	if name == "" {
+		name = e.Category
+	}
+	if v := r.FormValue("goal"); v != "" {
+		goal, err := strconv.ParseFloat(v, 64)
+		if err != nil || goal < 0 {
+			http.Error(w, "Objectif invalide", http.StatusBadRequest)
+			return
+		}
+		e.Goal = &goal
+	}
+
+	encryptedName, err := crypto.Encrypt(name)
+	if err != nil {
+		http.Error(w, "Erreur chiffrement", http.StatusInternalServerError)
+		return
+	}
+	e.Name = encryptedName
+
+	existing, err := db.GetEnvelopesByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	if idStr := r.FormValue("id"); idStr != "" {
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			http.Error(w, "ID invalide", http.StatusBadRequest)
+			return
+		}
+		if findEnvelope(existing, id) == nil {
+			http.Error(w, "Enveloppe non trouvee", http.StatusNotFound)
+			return
+		}
+		e.ID = id
+		if err := db.UpdateEnvelope(e); err != nil {
+			http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		e.Position = len(existing)
+		if _, err := db.CreateEnvelope(e); err != nil {
+			http.Error(w, "Erreur creation", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	EnvelopesAPI(w, r)
+}
+
+// DeleteEnvelope supprime une enveloppe ; son argent affecte revient a repartir
+func DeleteEnvelope(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID invalide", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DeleteEnvelope(id, user.ID); err != nil {
+		http.Error(w, "Erreur suppression", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// AssignEnvelope fixe le montant affecte a une enveloppe pour un mois
+func AssignEnvelope(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID invalide", http.StatusBadRequest)
+		return
+	}
+
+	envelopes, err := db.GetEnvelopesByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	if findEnvelope(envelopes, id) == nil {
+		http.Error(w, "Enveloppe non trouvee", http.StatusNotFound)
+		return
+	}
+
+	month, ok := envelopeMonth(r.FormValue("month"))
+	if !ok {
+		http.Error(w, "Mois invalide", http.StatusBadRequest)
+		return
+	}
+	amount, err := strconv.ParseFloat(r.FormValue("amount"), 64)
+	if err != nil {
+		http.Error(w, "Montant invalide", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.SetEnvelopeAllocation(user.ID, id, month, amount); err != nil {
+		http.Error(w, "Erreur enregistrement", http.StatusInternalServerError)
+		return
+	}
+
+	writeEnvelopeReport(w, user.ID, month)
+}
+
+// MoveEnvelope transfere explicitement de l'argent d'une enveloppe a une autre
+func MoveEnvelope(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	fromID, err1 := strconv.ParseInt(r.FormValue("fromId"), 10, 64)
+	toID, err2 := strconv.ParseInt(r.FormValue("toId"), 10, 64)
+	if err1 != nil || err2 != nil || fromID == toID {
+		http.Error(w, "Enveloppes invalides", http.StatusBadRequest)
+		return
+	}
+	amount, err := strconv.ParseFloat(r.FormValue("amount"), 64)
+	if err != nil || amount <= 0 {
+		http.Error(w, "Montant invalide", http.StatusBadRequest)
+		return
+	}
+	month, ok := envelopeMonth(r.FormValue("month"))
+	if !ok {
+		http.Error(w, "Mois invalide", http.StatusBadRequest)
+		return
+	}
+
+	envelopes, err := db.GetEnvelopesByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	if findEnvelope(envelopes, fromID) == nil || findEnvelope(envelopes, toID) == nil {
+		http.Error(w, "Enveloppe non trouvee", http.StatusNotFound)
+		return
+	}
+
+	err = db.CreateEnvelopeMove(db.EnvelopeMove{
+		UserID:         user.ID,
+		FromEnvelopeID: fromID,
+		ToEnvelopeID:   toID,
+		Amount:         amount,
+		Month:          month,
+	})
+	if err != nil {
+		http.Error(w, "Erreur enregistrement", http.StatusInternalServerError)
+		return
+	}
+
+	writeEnvelopeReport(w, user.ID, month)
+}
+
+// EnvelopeReportAPI retourne l'etat des enveloppes et le reste a affecter d'un mois
+func EnvelopeReportAPI(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	if !checkBudgetMode(w, user.ID, budget.ModeEnvelope) {
+		return
+	}
+
+	month, ok := envelopeMonth(r.URL.Query().Get("month"))
+	if !ok {
+		http.Error(w, "Mois invalide", http.StatusBadRequest)
+		return
+	}
+
+	writeEnvelopeReport(w, user.ID, month)
+}
+
+// writeEnvelopeReport calcule et renvoie le budget base zero du mois
+func writeEnvelopeReport(w http.ResponseWriter, userID int64, month time.Time) {
+	envelopes, err := loadEnvelopes(userID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	allocations, err := db.GetEnvelopeAllocationsByUserID(userID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	moves, err := db.GetEnvelopeMovesByUserID(userID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	txs, err := db.GetTransactionsByUserID(userID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(budget.ComputeEnvelopes(envelopes, allocations, moves, txs, month))
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"pilot-finance/internal/crypto"
+	"pilot-finance/internal/db"
+	"pilot-finance/internal/middleware"
+)
+
+// EventsAPI retourne les evenements ponctuels prevus en JSON
+func EventsAPI(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	events, err := db.GetPlannedEventsByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	accounts, _ := db.GetAccountsByUserID(user.ID)
+	accountMap := make(map[int64]string)
+	for _, acc := range accounts {
+		name := acc.Name
+		if decrypted, err := crypto.Decrypt(acc.Name); err == nil {
+			name = decrypted
+		}
+		accountMap[acc.ID] = name
+	}
+
+	result := make([]map[string]interface{}, len(events))
+	for i, e := range events {
+		description := e.Description
+		if decrypted, err := crypto.Decrypt(e.Description); err == nil {
+			description = decrypted
+		}
+
+		result[i] = map[string]interface{}{
+			"id":          e.ID,
+			"description": description,
+			"amount":      e.Amount,
+			"date":        e.Date.Format("2006-01-02"),
+			"accountId":   e.AccountID,
+			"accountName": accountMap[e.AccountID],
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// SaveEvent cree ou met a jour un evenement ponctuel prevu
+func SaveEvent(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	idStr := r.FormValue("id")
+	description := r.FormValue("description")
+	amountStr := r.FormValue("amount")
+	dateStr := r.FormValue("date")
+	opType := r.FormValue("type")
+	accountIDStr := r.FormValue("accountId")
+
+	if description == "" || amountStr == "" || dateStr == "" || accountIDStr == "" {
+		http.Error(w, "Champs requis manquants", http.StatusBadRequest)
+		return
+	}
+
+	amount, err := strconv.ParseFloat(amountStr, 64)
+	if err != nil {
+		http.Error(w, "Montant invalide", http.StatusBadRequest)
+		return
+	}
+
+	date, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		http.Error(w, "Date invalide", http.StatusBadRequest)
+		return
+	}
+
+	accountID, err := strconv.ParseInt(accountIDStr, 10, 64)
+	if err != nil {
+		http.Error(w, "Compte invalide", http.StatusBadRequest)
+		return
+	}
+	if acc, err := db.GetAccountByID(accountID, user.ID); err != nil || acc == nil {
+		http.Error(w, "Compte invalide", http.StatusBadRequest)
+		return
+	}
+
+	// Ajuster le signe selon le type
+	if opType == "expense" && amount > 0 {
+		amount = -amount
+	} else if opType == "income" && amount < 0 {
+		amount = -amount
+	}
+
+	// Chiffrer la description
+	encryptedDesc, err := crypto.Encrypt(description)
+	if err != nil {
+		http.Error(w, "Erreur chiffrement", http.StatusInternalServerError)
+		return
+	}
+
+	// Si un ID est fourni, c'est une mise a jour
+	if idStr != "" {
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			http.Error(w, "ID invalide", http.StatusBadRequest)
+			return
+		}
+		if err := db.UpdatePlannedEvent(id, user.ID, accountID, encryptedDesc, amount, date); err != nil {
+			http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if err := db.CreatePlannedEvent(user.ID, accountID, encryptedDesc, amount, date); err != nil {
+		http.Error(w, "Erreur creation", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// DeleteEvent supprime un evenement ponctuel prevu
+func DeleteEvent(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID invalide", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DeletePlannedEvent(id, user.ID); err != nil {
+		http.Error(w, "Erreur suppression", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"pilot-finance/internal/crypto"
+	"pilot-finance/internal/db"
+	"pilot-finance/internal/gains"
+	"pilot-finance/internal/middleware"
+)
+
+// GainsAPI retourne les plus-values realisees et latentes d'un compte-titres en JSON
+func GainsAPI(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	acc := requireAccount(w, r, user.ID)
+	if acc == nil {
+		return
+	}
+
+	// Methode du compte, surchargeable pour comparer PRU et FIFO
+	method := acc.CostBasisMethod
+	if m := strings.ToUpper(r.URL.Query().Get("method")); m == gains.MethodPRU || m == gains.MethodFIFO {
+		method = m
+	}
+
+	trades, err := db.GetTradesByAccount(acc.ID, user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	for i := range trades {
+		if decrypted, err := crypto.Decrypt(trades[i].Symbol); err == nil {
+			trades[i].Symbol = decrypted
+		}
+	}
+
+	quotes, _ := db.GetQuotesByAccount(acc.ID, user.ID)
+	prices := make(map[string]float64, len(quotes))
+	for _, q := range quotes {
+		if symbol, err := crypto.Decrypt(q.Symbol); err == nil {
+			prices[symbol] = q.Price
+		}
+	}
+
+	report, err := gains.Compute(trades, prices, method)
+	if err != nil {
+		if errors.Is(err, gains.ErrOversell) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	// Rapport annuel des plus-values realisees
+	if y := r.URL.Query().Get("year"); y != "" {
+		year, err := strconv.Atoi(y)
+		if err != nil {
+			http.Error(w, "Annee invalide", http.StatusBadRequest)
+			return
+		}
+		report = report.FilterYear(year)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"accountId":   acc.ID,
+		"accountName": acc.Name,
+		"trades":      trades,
+		"report":      report,
+	})
+}
+
+// CreateTrade enregistre un achat ou une vente sur un compte-titres
+func CreateTrade(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	acc := requireAccount(w, r, user.ID)
+	if acc == nil {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	symbol := strings.ToUpper(strings.TrimSpace(r.FormValue("symbol")))
+	side := strings.ToUpper(r.FormValue("side"))
+	if symbol == "" || (side != "BUY" && side != "SELL") {
+		http.Error(w, "Champs requis manquants", http.StatusBadRequest)
+		return
+	}
+
+	quantity, err := strconv.ParseFloat(r.FormValue("quantity"), 64)
+	if err != nil || quantity <= 0 {
+		http.Error(w, "Quantite invalide", http.StatusBadRequest)
+		return
+	}
+	price, err := strconv.ParseFloat(r.FormValue("price"), 64)
+	if err != nil || price < 0 {
+		http.Error(w, "Prix invalide", http.StatusBadRequest)
+		return
+	}
+	fees := 0.0
+	if f := r.FormValue("fees"); f != "" {
+		fees, err = strconv.ParseFloat(f, 64)
+		if err != nil || fees < 0 {
+			http.Error(w, "Frais invalides", http.StatusBadRequest)
+			return
+		}
+	}
+
+	date := time.Now()
+	if d := r.FormValue("date"); d != "" {
+		date, err = time.Parse("2006-01-02", d)
+		if err != nil {
+			http.Error(w, "Date invalide", http.StatusBadRequest)
+			return
+		}
+	}
+
+	encryptedSymbol, err := crypto.Encrypt(symbol)
+	if err != nil {
+		http.Error(w, "Erreur chiffrement", http.StatusInternalServerError)
+		return
+	}
+
+	if err := db.CreateTrade(user.ID, acc.ID, encryptedSymbol, side, quantity, price, fees, date); err != nil {
+		http.Error(w, "Erreur creation", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// DeleteTrade supprime un ordre
+func DeleteTrade(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	acc := requireAccount(w, r, user.ID)
+	if acc == nil {
+		return
+	}
+
+	tradeID, err := strconv.ParseInt(chi.URLParam(r, "tradeId"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID invalide", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DeleteTrade(tradeID, acc.ID, user.ID); err != nil {
+		http.Error(w, "Erreur suppression", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// SetQuote met a jour le dernier cours d'un titre pour le calcul des plus-values latentes
+func SetQuote(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	acc := requireAccount(w, r, user.ID)
+	if acc == nil {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	symbol := strings.ToUpper(strings.TrimSpace(r.FormValue("symbol")))
+	price, err := strconv.ParseFloat(r.FormValue("price"), 64)
+	if symbol == "" || err != nil || price < 0 {
+		http.Error(w, "Cours invalide", http.StatusBadRequest)
+		return
+	}
+
+	encryptedSymbol, err := crypto.Encrypt(symbol)
+	if err != nil {
+		http.Error(w, "Erreur chiffrement", http.StatusInternalServerError)
+		return
+	}
+
+	// Blind index par compte pour retrouver le cours sans dechiffrer
+	blindIndex := crypto.ComputeBlindIndex(strconv.FormatInt(acc.ID, 10) + ":" + symbol)
+	if err := db.UpsertQuote(user.ID, acc.ID, encryptedSymbol, blindIndex, price); err != nil {
+		http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// SetCostBasisMethod choisit la methode de prix de revient du compte (PRU ou FIFO)
+func SetCostBasisMethod(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	acc := requireAccount(w, r, user.ID)
+	if acc == nil {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	method := strings.ToUpper(r.FormValue("method"))
+	if method != gains.MethodPRU && method != gains.MethodFIFO {
+		http.Error(w, "Methode invalide", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.UpdateAccountCostBasisMethod(acc.ID, user.ID, method); err != nil {
+		http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,491 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"pilot-finance/internal/crypto"
+	"pilot-finance/internal/db"
+	"pilot-finance/internal/importer"
+	"pilot-finance/internal/middleware"
+	"pilot-finance/internal/rules"
+)
+
+// Taille maximale d'un releve importe
+const maxImportSize = 5 << 20
+
+// Nombre de lignes brutes renvoyees pour definir une correspondance CSV
+const previewLines = 10
+
+// errImport signale une erreur de saisie a renvoyer telle quelle au client
+type errImport struct{ msg string }
+
+func (e errImport) Error() string { return e.msg }
+
+// ImportStatement importe un releve bancaire dans un compte.
+// Sans commit=true, renvoie un apercu des operations sans rien enregistrer.
+func ImportStatement(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	acc := requireAccount(w, r, user.ID)
+	if acc == nil {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		http.Error(w, "Fichier trop volumineux ou invalide", http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Fichier requis", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Lecture du fichier impossible", http.StatusBadRequest)
+		return
+	}
+
+	format := strings.ToLower(r.FormValue("format"))
+	if format == "" {
+		format = "csv"
+	}
+
+	var st importer.Statement
+	var extra map[string]interface{}
+	switch format {
+	case "csv":
+		st, extra, err = parseCSVImport(r, user.ID, data)
+	case "ofx", "qfx":
+		st, err = importer.ParseOFX(data)
+		if err != nil {
+			err = errImport{err.Error()}
+		}
+	case "qif":
+		st, err = parseQIFImport(r, data)
+	case "camt", "camt053", "camt052":
+		st, err = importer.ParseCAMT(data)
+		if err != nil {
+			err = errImport{err.Error()}
+		}
+	default:
+		http.Error(w, "Format non supporte", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeImportError(w, err)
+		return
+	}
+
+	duplicates, err := markDuplicates(user.ID, acc.ID, &st)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"accountId":  acc.ID,
+		"format":     format,
+		"rows":       st.Rows,
+		"count":      len(st.Rows),
+		"duplicates": duplicates,
+		"total":      st.Total(),
+		"committed":  false,
+	}
+	for k, v := range extra {
+		response[k] = v
+	}
+
+	if st.OpeningBalance != nil {
+		response["openingBalance"] = *st.OpeningBalance
+	}
+
+	// Solde de fin du releve compare au solde saisi
+	if st.ClosingBalance != nil {
+		response["closingBalance"] = *st.ClosingBalance
+		response["balanceDate"] = st.BalanceDate
+		response["accountBalance"] = acc.Balance
+		response["balanceMatches"] = math.Abs(*st.ClosingBalance-acc.Balance) < 0.005
+	}
+
+	if r.FormValue("commit") == "true" {
+		// Decisions sur les doublons : {"<ligne>": "skip" | "merge" | "import"}
+		resolutions := map[int]string{}
+		if raw := r.FormValue("resolutions"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &resolutions); err != nil {
+				http.Error(w, "Decisions invalides", http.StatusBadRequest)
+				return
+			}
+		}
+		for line, action := range resolutions {
+			if action != resolveSkip && action != resolveMerge && action != resolveImport {
+				http.Error(w, fmt.Sprintf("Decision invalide ligne %d", line), http.StatusBadRequest)
+				return
+			}
+		}
+
+		summary, err := commitStatement(user.ID, acc, st, resolutions)
+		if err != nil {
+			http.Error(w, "Erreur import", http.StatusInternalServerError)
+			return
+		}
+		response["committed"] = true
+		response["created"] = summary.Created
+		response["merged"] = summary.Merged
+		response["skipped"] = summary.Skipped
+
+		// Mise a jour du solde sur demande explicite
+		if st.ClosingBalance != nil && r.FormValue("updateBalance") == "true" {
+			if err := db.UpdateAccountBalance(acc.ID, user.ID, *st.ClosingBalance); err != nil {
+				http.Error(w, "Erreur mise a jour solde", http.StatusInternalServerError)
+				return
+			}
+			response["balanceUpdated"] = true
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseCSVImport lit un CSV avec une correspondance enregistree (mappingId)
+// ou fournie en JSON (mapping), et l'enregistre si saveAs est renseigne.
+// Sans colonne de date, renvoie seulement l'en-tete et les premieres lignes.
+func parseCSVImport(r *http.Request, userID int64, data []byte) (importer.Statement, map[string]interface{}, error) {
+	var mapping importer.CSVMapping
+	if id := r.FormValue("mappingId"); id != "" {
+		mappingID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return importer.Statement{}, nil, errImport{"Correspondance invalide"}
+		}
+		saved, err := db.GetImportMappingByID(mappingID, userID)
+		if err != nil {
+			return importer.Statement{}, nil, err
+		}
+		if saved == nil {
+			return importer.Statement{}, nil, errImport{"Correspondance non trouvee"}
+		}
+		if err := json.Unmarshal([]byte(saved.Config), &mapping); err != nil {
+			return importer.Statement{}, nil, err
+		}
+	} else if raw := r.FormValue("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			return importer.Statement{}, nil, errImport{"Correspondance invalide"}
+		}
+	}
+
+	// Pas encore de correspondance : on montre le fichier brut
+	if mapping.DateColumn == 0 {
+		preview, err := importer.PreviewCSV(data, mapping, previewLines)
+		if err != nil {
+			return importer.Statement{}, nil, errImport{err.Error()}
+		}
+		return importer.Statement{}, map[string]interface{}{"preview": preview}, nil
+	}
+
+	st, err := importer.ParseCSV(data, mapping)
+	if err != nil {
+		return importer.Statement{}, nil, errImport{err.Error()}
+	}
+
+	extra := map[string]interface{}{"mapping": mapping}
+	if name := strings.TrimSpace(r.FormValue("saveAs")); name != "" {
+		config, _ := json.Marshal(mapping)
+		encryptedName, err := crypto.Encrypt(name)
+		if err != nil {
+			return importer.Statement{}, nil, err
+		}
+		id, err := db.CreateImportMapping(userID, encryptedName, string(config))
+		if err != nil {
+			return importer.Statement{}, nil, err
+		}
+		extra["mappingId"] = id
+	}
+	return st, extra, nil
+}
+
+// parseQIFImport lit un QIF avec l'ordre des dates (dateFormat) et une
+// correspondance des categories du fichier vers celles de Pilot (categoryMap, JSON)
+func parseQIFImport(r *http.Request, data []byte) (importer.Statement, error) {
+	categories := map[string]string{}
+	if raw := r.FormValue("categoryMap"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &categories); err != nil {
+			return importer.Statement{}, errImport{"Correspondance des categories invalide"}
+		}
+	}
+
+	st, err := importer.ParseQIF(data, r.FormValue("dateFormat"), categories)
+	if err != nil {
+		return importer.Statement{}, errImport{err.Error()}
+	}
+	return st, nil
+}
+
+// externalRef calcule l'index aveugle d'un identifiant banque, propre au compte
+func externalRef(accountID int64, reference string) string {
+	return crypto.ComputeBlindIndex(fmt.Sprintf("%d:%s", accountID, reference))
+}
+
+// fingerprint calcule l'empreinte d'une operation, propre au compte
+func fingerprint(accountID int64, row importer.Row) string {
+	return crypto.ComputeBlindIndex(importer.FingerprintKey(accountID, row))
+}
+
+// markDuplicates rapproche les operations du releve de l'historique du compte
+func markDuplicates(userID, accountID int64, st *importer.Statement) (int, error) {
+	if len(st.Rows) == 0 {
+		return 0, nil
+	}
+
+	from, to := importer.DuplicateWindow(st.Rows)
+	txs, err := db.GetTransactionsForMatching(accountID, userID, from, to)
+	if err != nil {
+		return 0, err
+	}
+	existing := make([]importer.Existing, 0, len(txs))
+	for _, t := range txs {
+		e := importer.Existing{ID: t.ID, Amount: t.Amount, Date: t.Date}
+		if t.Fingerprint != nil {
+			e.Fingerprint = *t.Fingerprint
+		}
+		if t.ExternalRef != nil {
+			e.ExternalRef = *t.ExternalRef
+		}
+		existing = append(existing, e)
+	}
+
+	return importer.MarkDuplicates(st.Rows, existing,
+		func(row importer.Row) string { return fingerprint(accountID, row) },
+		func(row importer.Row) string {
+			if row.Reference == "" {
+				return ""
+			}
+			return externalRef(accountID, row.Reference)
+		}), nil
+}
+
+// Decisions possibles pour un doublon a l'import
+const (
+	resolveSkip   = "skip"   // Ignorer la ligne du releve
+	resolveMerge  = "merge"  // Rattacher la ligne a la transaction existante
+	resolveImport = "import" // Creer la transaction malgre tout
+)
+
+// importSummary compte le sort des lignes d'un releve
+type importSummary struct {
+	Created int `json:"created"`
+	Merged  int `json:"merged"`
+	Skipped int `json:"skipped"`
+}
+
+// commitStatement enregistre les operations d'un releve sur le compte.
+// resolutions associe un numero de ligne a une decision ; par defaut les doublons
+// exacts sont ignores et les doublons probables importes.
+// Le solde du compte n'est pas modifie : il reste la valeur saisie par l'utilisateur.
+func commitStatement(userID int64, acc *db.Account, st importer.Statement, resolutions map[int]string) (importSummary, error) {
+	var summary importSummary
+	userRules, err := loadRules(userID)
+	if err != nil {
+		return summary, err
+	}
+
+	txs := make([]db.Transaction, 0, len(st.Rows))
+	for _, row := range st.Rows {
+		action := resolveImport
+		if row.Match == importer.MatchExact {
+			action = resolveSkip
+		}
+		if a, ok := resolutions[row.Line]; ok && row.Match != "" {
+			action = a
+		}
+
+		// Categorisation automatique, sans ecraser la categorie du fichier
+		target := rules.Target{
+			AccountID:   acc.ID,
+			Amount:      row.Amount,
+			Description: row.Description,
+			Category:    row.Category,
+			Payee:       row.Counterparty,
+		}
+		rules.Apply(userRules, &target, false)
+		category, payee, tags, err := encryptClassification(target)
+		if err != nil {
+			return summary, err
+		}
+
+		var ref *string
+		if row.Reference != "" {
+			r := externalRef(acc.ID, row.Reference)
+			ref = &r
+		}
+		fp := fingerprint(acc.ID, row)
+
+		switch action {
+		case resolveSkip:
+			summary.Skipped++
+			continue
+		case resolveMerge:
+			if err := db.MergeImportedTransaction(row.DuplicateOf, userID, category, ref, &fp); err != nil {
+				return summary, err
+			}
+			summary.Merged++
+			continue
+		}
+
+		description, err := crypto.Encrypt(row.Description)
+		if err != nil {
+			return summary, err
+		}
+		txs = append(txs, db.Transaction{
+			UserID:      userID,
+			AccountID:   acc.ID,
+			Amount:      row.Amount,
+			Description: description,
+			Category:    category,
+			Payee:       payee,
+			Tags:        tags,
+			Date:        row.Date,
+			ExternalRef: ref,
+			Fingerprint: &fp,
+			SearchTokens: searchTokens(userID, row.Description, target.Payee,
+				strings.Join(target.Tags, " ")),
+		})
+	}
+
+	if len(txs) > 0 {
+		if err := db.CreateTransactions(txs); err != nil {
+			return summary, err
+		}
+	}
+	summary.Created = len(txs)
+	return summary, nil
+}
+
+func writeImportError(w http.ResponseWriter, err error) {
+	var e errImport
+	if errors.As(err, &e) {
+		http.Error(w, e.msg, http.StatusUnprocessableEntity)
+		return
+	}
+	http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+}
+
+// ImportMappingsAPI retourne les correspondances CSV enregistrees en JSON
+func ImportMappingsAPI(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	mappings, err := db.GetImportMappingsByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]map[string]interface{}, 0, len(mappings))
+	for _, m := range mappings {
+		name := m.Name
+		if decrypted, err := crypto.Decrypt(m.Name); err == nil {
+			name = decrypted
+		}
+		result = append(result, map[string]interface{}{
+			"id":      m.ID,
+			"name":    name,
+			"mapping": json.RawMessage(m.Config),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// DeleteImportMapping supprime une correspondance CSV enregistree
+func DeleteImportMapping(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID invalide", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DeleteImportMapping(id, user.ID); err != nil {
+		http.Error(w, "Erreur suppression", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// ExportTransactions exporte les transactions d'un compte au format QIF
+func ExportTransactions(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	acc := requireAccount(w, r, user.ID)
+	if acc == nil {
+		return
+	}
+
+	if format := r.URL.Query().Get("format"); format != "" && format != "qif" {
+		http.Error(w, "Format non supporte", http.StatusBadRequest)
+		return
+	}
+
+	txs, err := db.GetTransactionsByAccount(acc.ID, user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	// Ordre chronologique pour les logiciels qui recalculent les soldes
+	rows := make([]importer.Row, 0, len(txs))
+	for i := len(txs) - 1; i >= 0; i-- {
+		t := txs[i]
+		description := t.Description
+		if decrypted, err := crypto.Decrypt(t.Description); err == nil {
+			description = decrypted
+		}
+		row := importer.Row{Date: t.Date, Amount: t.Amount, Description: description}
+		if t.Category != nil {
+			row.Category = *t.Category
+		}
+		rows = append(rows, row)
+	}
+
+	filename := strings.Map(func(c rune) rune {
+		if c == '"' || c == '/' || c == '\\' || c < ' ' {
+			return '_'
+		}
+		return c
+	}, acc.Name)
+	w.Header().Set("Content-Type", "application/qif; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.qif"`, filename))
+	importer.WriteQIF(w, rows)
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"pilot-finance/internal/crypto"
+	"pilot-finance/internal/db"
+	"pilot-finance/internal/importer"
+	"pilot-finance/internal/middleware"
+)
+
+// Couleurs attribuees aux comptes migres
+var migrationColors = []string{"#3b82f6", "#10b981", "#f59e0b", "#8b5cf6", "#ef4444", "#06b6d4"}
+
+// MigrateImport reprend les donnees d'un autre logiciel (Firefly III, YNAB, HomeBank) :
+// comptes, transactions, categories et operations recurrentes.
+// Sans commit=true, renvoie seulement le rapport de ce qui serait cree.
+func MigrateImport(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	if err := r.ParseMultipartForm(maxImportSize); err != nil {
+		http.Error(w, "Fichier trop volumineux ou invalide", http.StatusBadRequest)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Fichier requis", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Lecture du fichier impossible", http.StatusBadRequest)
+		return
+	}
+
+	var m importer.Migration
+	switch r.FormValue("source") {
+	case importer.SourceFireflyCSV:
+		m, err = importer.ParseFireflyCSV(data)
+	case importer.SourceFireflyJSON:
+		m, err = importer.ParseFireflyJSON(data)
+	case importer.SourceYNAB:
+		m, err = importer.ParseYNAB(data, r.FormValue("dateFormat"))
+	case importer.SourceHomebank:
+		m, err = importer.ParseHomebank(data)
+	default:
+		http.Error(w, "Source non supportee", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	accounts, err := db.GetAccountsByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	existing := make(map[string]int64, len(accounts))
+	for _, acc := range accounts {
+		if name, err := crypto.Decrypt(acc.Name); err == nil {
+			existing[strings.ToLower(name)] = acc.ID
+		}
+	}
+
+	for _, a := range m.Accounts {
+		if _, ok := existing[strings.ToLower(a.Name)]; ok {
+			m.Warnings = append(m.Warnings, fmt.Sprintf("Un compte nomme %s existe deja, un second sera cree", a.Name))
+		}
+	}
+
+	// Les recurrences doivent viser un compte migre ou existant
+	migrated := make(map[string]bool, len(m.Accounts))
+	for _, a := range m.Accounts {
+		migrated[strings.ToLower(a.Name)] = true
+	}
+	known := func(name string) bool {
+		_, ok := existing[strings.ToLower(name)]
+		return migrated[strings.ToLower(name)] || ok
+	}
+	recurrings := m.Recurrings[:0]
+	for _, rec := range m.Recurrings {
+		if !known(rec.Account) || (rec.ToAccount != "" && !known(rec.ToAccount)) {
+			m.Warnings = append(m.Warnings, fmt.Sprintf("Operation recurrente sans compte ignoree : %s", rec.Description))
+			continue
+		}
+		recurrings = append(recurrings, rec)
+	}
+	m.Recurrings = recurrings
+
+	response := map[string]interface{}{
+		"report":    m.Report(),
+		"committed": false,
+	}
+
+	if r.FormValue("commit") == "true" {
+		created, err := commitMigration(user.ID, len(accounts), m, existing)
+		if err != nil {
+			http.Error(w, "Erreur import", http.StatusInternalServerError)
+			return
+		}
+		response["committed"] = true
+		response["accountIds"] = created
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// commitMigration cree les comptes avec leur historique puis les recurrences, en une
+// seule transaction : un echec n'en laisse aucune trace et la migration peut etre
+// relancee. Le solde de chaque compte est celui obtenu apres la derniere operation.
+func commitMigration(userID int64, position int, m importer.Migration, existing map[string]int64) ([]int64, error) {
+	accounts := make([]db.MigratedAccount, 0, len(m.Accounts))
+	for i, a := range m.Accounts {
+		name, err := crypto.Encrypt(a.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		txs := make([]db.Transaction, 0, len(a.Rows))
+		for _, row := range a.Rows {
+			description, err := crypto.Encrypt(row.Description)
+			if err != nil {
+				return nil, err
+			}
+			tx := db.Transaction{
+				Amount:       row.Amount,
+				Description:  description,
+				Date:         row.Date,
+				SearchTokens: searchTokens(userID, row.Description, row.Counterparty),
+			}
+			if row.Category != "" {
+				category := row.Category
+				tx.Category = &category
+			}
+			txs = append(txs, tx)
+		}
+
+		accounts = append(accounts, db.MigratedAccount{
+			Key:          strings.ToLower(a.Name),
+			Name:         name,
+			Balance:      a.Balance(),
+			Color:        migrationColors[i%len(migrationColors)],
+			Position:     position + i,
+			Transactions: txs,
+		})
+	}
+
+	recurrings := make([]db.MigratedRecurring, 0, len(m.Recurrings))
+	for _, rec := range m.Recurrings {
+		description, err := crypto.Encrypt(rec.Description)
+		if err != nil {
+			return nil, err
+		}
+		day := rec.DayOfMonth
+		if day < 1 || day > 31 {
+			day = 1
+		}
+		r := db.MigratedRecurring{
+			Operation: db.RecurringOperation{
+				AccountID:   existing[strings.ToLower(rec.Account)],
+				Amount:      rec.Amount,
+				Description: description,
+				DayOfMonth:  day,
+			},
+			AccountKey: strings.ToLower(rec.Account),
+		}
+		if rec.ToAccount != "" {
+			id := existing[strings.ToLower(rec.ToAccount)]
+			r.Operation.ToAccountID = &id
+			r.ToAccountKey = strings.ToLower(rec.ToAccount)
+		}
+		recurrings = append(recurrings, r)
+	}
+
+	return db.CreateMigration(userID, accounts, recurrings)
+}
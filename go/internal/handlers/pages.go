@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"os"
+	"time"
 
 	"pilot-finance/internal/crypto"
 	"pilot-finance/internal/db"
@@ -80,22 +81,14 @@ func Dashboard(w http.ResponseWriter, r *http.Request) {
 			accounts[i].Name = decrypted
 		}
 	}
+	decryptPockets(accounts)
 
 	// Calculer les projections avec interets composes
 	years := 5
-	projData := projection.Calculate(accounts, years)
+	projData := projection.CalculateWithOptions(accounts, years, projectionOptions(user.ID))
 
 	// Donnees pour le graphique camembert
-	var pieData []map[string]interface{}
-	for _, acc := range accounts {
-		if acc.Balance > 0 {
-			pieData = append(pieData, map[string]interface{}{
-				"name":  acc.Name,
-				"value": acc.Balance,
-				"color": acc.Color,
-			})
-		}
-	}
+	pieData := pieSlices(accounts)
 
 	// Projection finale (annee N)
 	var projectionTotal float64
@@ -119,10 +112,12 @@ func Dashboard(w http.ResponseWriter, r *http.Request) {
 		"AccountColors":   accountColors,
 		"TotalBalance":    projData.TotalBalance,
 		"TotalInterests":  projData.TotalInterests,
+		"TotalFees":       projData.TotalFees,
 		"Years":           years,
 		"ProjectionTotal": projectionTotal,
 		"ProjectionData":  projData.Projection,
 		"PieData":         pieData,
+		"Maturities":      projection.UpcomingMaturities(accounts, time.Now(), 12),
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -150,6 +145,7 @@ func AccountsPage(w http.ResponseWriter, r *http.Request) {
 		}
 		accountMap[accounts[i].ID] = accounts[i].Name
 	}
+	decryptPockets(accounts)
 
 	// Calculer les yield payouts (intérêts non réinvestis)
 	yieldPayouts := projection.CalculateYieldPayouts(accounts, accountMap)
@@ -176,6 +172,7 @@ func AccountsPage(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
+	allocations := allocationData(user.ID, accountMap)
 	for _, rec := range recurrings {
 		description := rec.Description
 		if decrypted, err := crypto.Decrypt(rec.Description); err == nil {
@@ -204,6 +201,8 @@ func AccountsPage(w http.ResponseWriter, r *http.Request) {
 			"ToAccountName": toAccountName,
 			"IsActive":      rec.IsActive,
 			"IsYieldPayout": false,
+			"Category":      rec.Category,
+			"Allocations":   allocations[rec.ID],
 		})
 	}
 
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"pilot-finance/internal/db"
+	"pilot-finance/internal/ledger"
+	"pilot-finance/internal/middleware"
+)
+
+// reconciliationState calcule l'ecart entre le solde d'un releve (date, balance)
+// et le solde pointe du compte depuis le dernier point de controle.
+// Sans point de controle, le solde d'ouverture est fourni par opening (0 par defaut).
+func reconciliationState(w http.ResponseWriter, r *http.Request, userID int64, acc *db.Account) (*ledger.Reconciliation, bool) {
+	date, err := time.ParseInLocation("2006-01-02", r.FormValue("date"), time.Local)
+	if err != nil {
+		http.Error(w, "Date invalide", http.StatusBadRequest)
+		return nil, false
+	}
+	balance, err := strconv.ParseFloat(r.FormValue("balance"), 64)
+	if err != nil {
+		http.Error(w, "Solde invalide", http.StatusBadRequest)
+		return nil, false
+	}
+
+	checkpoints, err := db.GetReconciliationsByAccount(acc.ID, userID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	opening := 0.0
+	if len(checkpoints) > 0 {
+		last := checkpoints[0]
+		if date.Before(last.StatementDate) {
+			http.Error(w, "Date anterieure au dernier rapprochement", http.StatusBadRequest)
+			return nil, false
+		}
+		opening = last.StatementBalance
+	} else if v := r.FormValue("opening"); v != "" {
+		opening, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			http.Error(w, "Solde d'ouverture invalide", http.StatusBadRequest)
+			return nil, false
+		}
+	}
+
+	txs, err := db.GetTransactionsByAccount(acc.ID, userID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return nil, false
+	}
+	for i := range txs {
+		decryptTransaction(&txs[i])
+	}
+
+	rec := ledger.Reconcile(opening, balance, date, txs)
+	return &rec, true
+}
+
+// ReconcileAPI retourne l'etat du rapprochement d'un compte pour un solde de releve
+func ReconcileAPI(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	acc := requireAccount(w, r, user.ID)
+	if acc == nil {
+		return
+	}
+
+	rec, ok := reconciliationState(w, r, user.ID, acc)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"accountId":      acc.ID,
+		"accountBalance": acc.Balance,
+		// Ecart entre le solde saisi sur le compte et l'historique des transactions
+		"accountDifference": math.Round((acc.Balance-rec.LedgerBalance)*100) / 100,
+		"reconciliation":    rec,
+	})
+}
+
+// SetCleared pointe ou depointe une transaction
+func SetCleared(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID invalide", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+	cleared := r.FormValue("cleared") != "false"
+
+	ok, err := db.SetTransactionCleared(id, user.ID, cleared)
+	if err != nil {
+		http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "Transaction non trouvee ou deja rapprochee", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"id": id, "cleared": cleared})
+}
+
+// Reconcile enregistre un point de controle une fois l'ecart ramene a zero.
+// Avec updateBalance=true, le solde du compte prend la valeur du releve.
+func Reconcile(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	acc := requireAccount(w, r, user.ID)
+	if acc == nil {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	rec, ok := reconciliationState(w, r, user.ID, acc)
+	if !ok {
+		return
+	}
+	if !rec.Balanced {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":          "Ecart non nul",
+			"reconciliation": rec,
+		})
+		return
+	}
+
+	id, err := db.CreateReconciliation(db.Reconciliation{
+		UserID:           user.ID,
+		AccountID:        acc.ID,
+		StatementDate:    rec.StatementDate,
+		StatementBalance: rec.StatementBalance,
+		OpeningBalance:   rec.OpeningBalance,
+	})
+	if err != nil {
+		http.Error(w, "Erreur creation", http.StatusInternalServerError)
+		return
+	}
+
+	if r.FormValue("updateBalance") == "true" {
+		if err := db.UpdateAccountBalance(acc.ID, user.ID, rec.StatementBalance); err != nil {
+			http.Error(w, "Erreur mise a jour solde", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":             id,
+		"reconciliation": rec,
+	})
+}
+
+// ReconciliationsAPI retourne l'historique des points de controle d'un compte
+func ReconciliationsAPI(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	acc := requireAccount(w, r, user.ID)
+	if acc == nil {
+		return
+	}
+
+	recs, err := db.GetReconciliationsByAccount(acc.ID, user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	if recs == nil {
+		recs = []db.Reconciliation{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(recs)
+}
@@ -1,13 +1,19 @@
 package handlers
 
 import (
+	"errors"
+	"log"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
 	"pilot-finance/internal/crypto"
 	"pilot-finance/internal/db"
+	"pilot-finance/internal/ledger"
 	"pilot-finance/internal/middleware"
 	"pilot-finance/internal/projection"
 	"pilot-finance/internal/templates"
@@ -71,6 +77,14 @@ func CreateRecurring(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	ids := []int64{accountID}
+	if toAccountID != nil {
+		ids = append(ids, *toAccountID)
+	}
+	if !checkOwnedAccounts(w, user.ID, ids...) {
+		return
+	}
+
 	// Ajuster le signe selon le type
 	if opType == "expense" && amount > 0 {
 		amount = -amount
@@ -85,14 +99,14 @@ func CreateRecurring(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "ID invalide", http.StatusBadRequest)
 			return
 		}
-		err = db.UpdateRecurring(id, user.ID, encryptedDesc, amount, day, toAccountID)
+		err = db.UpdateRecurring(id, user.ID, encryptedDesc, amount, day, toAccountID, recurringCategory(r, toAccountID))
 		if err != nil {
 			http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
 			return
 		}
 	} else {
 		// Creation
-		err = db.CreateRecurring(user.ID, accountID, toAccountID, encryptedDesc, amount, day)
+		err = db.CreateRecurring(user.ID, accountID, toAccountID, encryptedDesc, amount, day, recurringCategory(r, toAccountID))
 		if err != nil {
 			http.Error(w, "Erreur creation", http.StatusInternalServerError)
 			return
@@ -146,12 +160,15 @@ func UpdateRecurring(w http.ResponseWriter, r *http.Request) {
 			toAccountID = &tid
 		}
 	}
+	if toAccountID != nil && !checkOwnedAccounts(w, user.ID, *toAccountID) {
+		return
+	}
 
 	if opType == "expense" {
 		amount = -amount
 	}
 
-	err = db.UpdateRecurring(id, user.ID, encryptedDesc, amount, day, toAccountID)
+	err = db.UpdateRecurring(id, user.ID, encryptedDesc, amount, day, toAccountID, recurringCategory(r, toAccountID))
 	if err != nil {
 		http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
 		return
@@ -185,6 +202,31 @@ func DeleteRecurring(w http.ResponseWriter, r *http.Request) {
 	renderRecurringTable(w, user.ID)
 }
 
+// checkOwnedAccounts verifie que les comptes appartiennent a l'utilisateur
+func checkOwnedAccounts(w http.ResponseWriter, userID int64, ids ...int64) bool {
+	for _, id := range ids {
+		acc, err := db.GetAccountByID(id, userID)
+		if err != nil {
+			http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+			return false
+		}
+		if acc == nil {
+			http.Error(w, "Compte non trouve", http.StatusNotFound)
+			return false
+		}
+	}
+	return true
+}
+
+// recurringCategory lit la categorie budgetaire d'une operation ; un virement n'en a pas
+func recurringCategory(r *http.Request, toAccountID *int64) *string {
+	category := strings.TrimSpace(r.FormValue("category"))
+	if category == "" || toAccountID != nil {
+		return nil
+	}
+	return &category
+}
+
 // renderRecurringTable rend le tableau des operations recurrentes en HTML
 func renderRecurringTable(w http.ResponseWriter, userID int64) {
 	recurrings, _ := db.GetRecurringByUserID(userID)
@@ -223,6 +265,7 @@ func renderRecurringTable(w http.ResponseWriter, userID int64) {
 		})
 	}
 
+	allocations := allocationData(userID, accountMap)
 	for _, rec := range recurrings {
 		description := rec.Description
 		if decrypted, err := crypto.Decrypt(rec.Description); err == nil {
@@ -245,6 +288,8 @@ func renderRecurringTable(w http.ResponseWriter, userID int64) {
 			"ToAccountName": toAccountName,
 			"IsActive":      rec.IsActive,
 			"IsYieldPayout": false,
+			"Category":      rec.Category,
+			"Allocations":   allocations[rec.ID],
 		})
 	}
 
@@ -253,3 +298,130 @@ func renderRecurringTable(w http.ResponseWriter, userID int64) {
 		"Recurrings": recurringData,
 	})
 }
+
+// RunRecurring execute les operations recurrentes echues de l'utilisateur
+func RunRecurring(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	if _, err := runDueRecurring(user.ID, time.Now()); err != nil {
+		http.Error(w, "Erreur execution", http.StatusInternalServerError)
+		return
+	}
+
+	renderRecurringTable(w, user.ID)
+}
+
+// RunDueRecurring execute les operations recurrentes echues de tous les utilisateurs
+// (execution automatique, RECURRING_AUTORUN)
+func RunDueRecurring(now time.Time) {
+	users, err := db.GetAllUsers()
+	if err != nil {
+		log.Printf("Operations recurrentes: %v", err)
+		return
+	}
+	for _, u := range users {
+		n, err := runDueRecurring(u.ID, now)
+		if err != nil {
+			log.Printf("Operations recurrentes (utilisateur %d): %v", u.ID, err)
+		}
+		if n > 0 {
+			log.Printf("Operations recurrentes (utilisateur %d): %d executee(s)", u.ID, n)
+		}
+	}
+}
+
+// runDueRecurring execute les operations d'un utilisateur dont le jour est atteint ce
+// mois-ci : l'ecriture a la date prevue, puis pour un revenu les virements de ses
+// regles de repartition. Une operation portant sur un compte d'un autre utilisateur
+// est ignoree. Retourne le nombre d'operations executees.
+func runDueRecurring(userID int64, now time.Time) (int, error) {
+	recurrings, err := db.GetRecurringByUserID(userID)
+	if err != nil {
+		return 0, err
+	}
+	rules, err := db.GetAllocationRulesByUserID(userID)
+	if err != nil {
+		return 0, err
+	}
+	rulesByRecurring := db.AllocationRulesByRecurring(rules)
+	accounts, err := db.GetAccountsByUserID(userID)
+	if err != nil {
+		return 0, err
+	}
+	owned := make(map[int64]bool, len(accounts))
+	for _, acc := range accounts {
+		owned[acc.ID] = true
+	}
+
+	executed := 0
+	for _, rec := range recurrings {
+		if !projection.IsDue(rec, now) {
+			continue
+		}
+		if !owned[rec.AccountID] || (rec.ToAccountID != nil && !owned[*rec.ToAccountID]) {
+			continue
+		}
+		description := rec.Description
+		if decrypted, err := crypto.Decrypt(rec.Description); err == nil {
+			description = decrypted
+		}
+		date := projection.RunDate(rec, now)
+		tokens := searchTokens(userID, description)
+
+		var run db.RecurringRun
+		if rec.ToAccountID != nil {
+			amount := math.Abs(rec.Amount)
+			run.Transfers = append(run.Transfers, [2]db.Transaction{
+				{UserID: userID, AccountID: rec.AccountID, Amount: -amount, Description: rec.Description, Date: date, SearchTokens: tokens},
+				{UserID: userID, AccountID: *rec.ToAccountID, Amount: amount, Description: rec.Description, Date: date, SearchTokens: tokens},
+			})
+		} else {
+			run.Entries = append(run.Entries, db.Transaction{
+				UserID: userID, AccountID: rec.AccountID, Amount: rec.Amount, Description: rec.Description,
+				Category: rec.Category, Date: date, SearchTokens: tokens,
+			})
+			transfers, err := allocationTransfers(userID, rec, description, date, rulesByRecurring[rec.ID])
+			if err != nil {
+				return executed, err
+			}
+			run.Transfers = append(run.Transfers, transfers...)
+		}
+
+		err := db.RunRecurring(rec, date, run)
+		if errors.Is(err, db.ErrRecurringAlreadyRun) {
+			continue
+		}
+		if err != nil {
+			return executed, err
+		}
+		executed++
+	}
+	return executed, nil
+}
+
+// allocationTransfers prepare les virements de repartition d'un revenu recurrent
+func allocationTransfers(userID int64, rec db.RecurringOperation, description string, date time.Time, rules []db.AllocationRule) ([][2]db.Transaction, error) {
+	allocations := ledger.AllocateIncome(rec.Amount, rules)
+	if len(allocations) == 0 {
+		return nil, nil
+	}
+	label := "Repartition " + description
+	encrypted, err := crypto.Encrypt(label)
+	if err != nil {
+		return nil, err
+	}
+	tokens := searchTokens(userID, label)
+
+	transfers := make([][2]db.Transaction, 0, len(allocations))
+	for _, a := range allocations {
+		transfers = append(transfers, [2]db.Transaction{
+			{UserID: userID, AccountID: rec.AccountID, Amount: -a.Amount, Description: encrypted, Date: date, SearchTokens: tokens},
+			{UserID: userID, AccountID: a.ToAccountID, Amount: a.Amount, Description: encrypted, Date: date, SearchTokens: tokens},
+		})
+	}
+	return transfers, nil
+}
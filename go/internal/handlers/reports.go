@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"pilot-finance/internal/db"
+	"pilot-finance/internal/ledger"
+	"pilot-finance/internal/middleware"
+)
+
+// reportPeriod lit la periode from/to (YYYY-MM-DD), par defaut le mois en cours
+func reportPeriod(r *http.Request) (time.Time, time.Time, bool) {
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+	to := from.AddDate(0, 1, -1)
+
+	if v := r.URL.Query().Get("from"); v != "" {
+		d, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return from, to, false
+		}
+		from = d
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		d, err := time.ParseInLocation("2006-01-02", v, time.Local)
+		if err != nil {
+			return from, to, false
+		}
+		to = d
+	}
+	return from, to, !to.Before(from)
+}
+
+// CategoryReportAPI retourne les totaux par categorie sur une periode, ventilations comprises
+func CategoryReportAPI(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	from, to, ok := reportPeriod(r)
+	if !ok {
+		http.Error(w, "Periode invalide", http.StatusBadRequest)
+		return
+	}
+
+	txs, err := db.GetTransactionsByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"from":       from.Format("2006-01-02"),
+		"to":         to.Format("2006-01-02"),
+		"categories": ledger.CategoryTotals(txs, from, to),
+	})
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"pilot-finance/internal/crypto"
+	"pilot-finance/internal/db"
+	"pilot-finance/internal/middleware"
+	"pilot-finance/internal/rules"
+)
+
+// loadRules charge et dechiffre les regles de categorisation d'un utilisateur
+func loadRules(userID int64) ([]rules.Rule, error) {
+	stored, err := db.GetRulesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]rules.Rule, 0, len(stored))
+	for _, s := range stored {
+		definition, err := crypto.Decrypt(s.Definition)
+		if err != nil {
+			continue
+		}
+		var rule rules.Rule
+		if err := json.Unmarshal([]byte(definition), &rule); err != nil {
+			continue
+		}
+		rule.ID = s.ID
+		rule.IsActive = s.IsActive
+		result = append(result, rule)
+	}
+	return result, nil
+}
+
+// RulesAPI retourne les regles de categorisation en JSON
+func RulesAPI(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	result, err := loadRules(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// SaveRule cree ou met a jour une regle de categorisation
+func SaveRule(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	rule := rules.Rule{
+		Name:                strings.TrimSpace(r.FormValue("name")),
+		DescriptionContains: strings.TrimSpace(r.FormValue("descriptionContains")),
+		Category:            strings.TrimSpace(r.FormValue("category")),
+		Payee:               strings.TrimSpace(r.FormValue("payee")),
+		Tags:                rules.SplitTags(r.FormValue("tags")),
+	}
+	isActive := r.FormValue("isActive") != "false"
+
+	if v := r.FormValue("amountMin"); v != "" {
+		min, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			http.Error(w, "Montant minimum invalide", http.StatusBadRequest)
+			return
+		}
+		rule.AmountMin = &min
+	}
+	if v := r.FormValue("amountMax"); v != "" {
+		max, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			http.Error(w, "Montant maximum invalide", http.StatusBadRequest)
+			return
+		}
+		rule.AmountMax = &max
+	}
+	if v := r.FormValue("accountId"); v != "" && v != "0" {
+		accountID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Compte invalide", http.StatusBadRequest)
+			return
+		}
+		acc, err := db.GetAccountByID(accountID, user.ID)
+		if err != nil || acc == nil {
+			http.Error(w, "Compte non trouve", http.StatusNotFound)
+			return
+		}
+		rule.AccountID = &accountID
+	}
+
+	if err := rule.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	definition, _ := json.Marshal(rule)
+	encrypted, err := crypto.Encrypt(string(definition))
+	if err != nil {
+		http.Error(w, "Erreur chiffrement", http.StatusInternalServerError)
+		return
+	}
+
+	existing, err := db.GetRulesByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	position := len(existing)
+	if v := r.FormValue("position"); v != "" {
+		position, _ = strconv.Atoi(v)
+	}
+
+	if idStr := r.FormValue("id"); idStr != "" {
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			http.Error(w, "ID invalide", http.StatusBadRequest)
+			return
+		}
+		if r.FormValue("position") == "" {
+			for _, e := range existing {
+				if e.ID == id {
+					position = e.Position
+				}
+			}
+		}
+		if err := db.UpdateRule(id, user.ID, encrypted, position, isActive); err != nil {
+			http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		if _, err := db.CreateRule(user.ID, encrypted, position, isActive); err != nil {
+			http.Error(w, "Erreur creation", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	RulesAPI(w, r)
+}
+
+// DeleteRule supprime une regle de categorisation
+func DeleteRule(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID invalide", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.DeleteRule(id, user.ID); err != nil {
+		http.Error(w, "Erreur suppression", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// ApplyRules re-applique les regles aux transactions existantes.
+// Par defaut seules les informations manquantes sont completees ; overwrite=true
+// remplace aussi les categories et tiers deja renseignes.
+func ApplyRules(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+	overwrite := r.FormValue("overwrite") == "true"
+
+	userRules, err := loadRules(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	var txs []db.Transaction
+	if v := r.FormValue("accountId"); v != "" {
+		accountID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Compte invalide", http.StatusBadRequest)
+			return
+		}
+		txs, err = db.GetTransactionsByAccount(accountID, user.ID)
+		if err != nil {
+			http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+			return
+		}
+	} else {
+		txs, err = db.GetTransactionsByUserID(user.ID)
+		if err != nil {
+			http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	updated := 0
+	for _, t := range txs {
+		if t.LinkedID != nil {
+			continue
+		}
+		decryptTransaction(&t)
+		target := ruleTarget(t)
+		if !rules.Apply(userRules, &target, overwrite) {
+			continue
+		}
+		category, payee, tags, err := encryptClassification(target)
+		if err != nil {
+			http.Error(w, "Erreur chiffrement", http.StatusInternalServerError)
+			return
+		}
+		tokens := searchTokens(user.ID, target.Description, target.Payee, strings.Join(target.Tags, " "))
+		if err := db.UpdateTransactionClassification(t.ID, user.ID, category, payee, tags, tokens); err != nil {
+			http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+			return
+		}
+		updated++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"checked": len(txs),
+		"updated": updated,
+	})
+}
+
+// ruleTarget prepare une transaction dechiffree pour l'application des regles
+func ruleTarget(t db.Transaction) rules.Target {
+	target := rules.Target{AccountID: t.AccountID, Amount: t.Amount, Description: t.Description}
+	if t.Category != nil {
+		target.Category = *t.Category
+	}
+	if t.Payee != nil {
+		target.Payee = *t.Payee
+	}
+	if t.Tags != nil {
+		target.Tags = rules.SplitTags(*t.Tags)
+	}
+	return target
+}
+
+// encryptClassification retourne la categorie en clair, le tiers et les etiquettes chiffres
+func encryptClassification(target rules.Target) (category, payee, tags *string, err error) {
+	if target.Category != "" {
+		c := target.Category
+		category = &c
+	}
+	if payee, err = encryptOptional(target.Payee); err != nil {
+		return nil, nil, nil, err
+	}
+	if tags, err = encryptOptional(rules.JoinTags(target.Tags)); err != nil {
+		return nil, nil, nil, err
+	}
+	return category, payee, tags, nil
+}
+
+// encryptOptional chiffre une valeur facultative (nil si vide)
+func encryptOptional(value string) (*string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	encrypted, err := crypto.Encrypt(value)
+	if err != nil {
+		return nil, err
+	}
+	return &encrypted, nil
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"pilot-finance/internal/crypto"
+	"pilot-finance/internal/db"
+	"pilot-finance/internal/middleware"
+	"pilot-finance/internal/rules"
+	"pilot-finance/internal/textnorm"
+)
+
+// Longueur minimale d'un mot-cle indexe
+const minTokenLength = 2
+
+// searchTokens calcule les mots-cles de recherche en index aveugle. La cle
+// inclut l'utilisateur pour que deux comptes ne partagent pas les memes jetons.
+func searchTokens(userID int64, texts ...string) []string {
+	seen := make(map[string]bool)
+	tokens := []string{}
+	for _, text := range texts {
+		for _, word := range textnorm.Tokens(text) {
+			if len([]rune(word)) < minTokenLength || seen[word] {
+				continue
+			}
+			seen[word] = true
+			tokens = append(tokens, crypto.ComputeBlindIndex(fmt.Sprintf("search:%d:%s", userID, word)))
+		}
+	}
+	return tokens
+}
+
+// transactionTokens calcule les mots-cles d'une transaction dechiffree
+func transactionTokens(userID int64, t db.Transaction) []string {
+	texts := []string{t.Description}
+	if t.Payee != nil {
+		texts = append(texts, *t.Payee)
+	}
+	if t.Tags != nil {
+		texts = append(texts, strings.Join(rules.SplitTags(*t.Tags), " "))
+	}
+	return searchTokens(userID, texts...)
+}
+
+// SearchAPI recherche les transactions par mots-cles (libelle, tiers, etiquettes),
+// periode, montant, categorie et compte
+func SearchAPI(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := db.TransactionFilter{Tokens: searchTokens(user.ID, q.Get("q"))}
+	if strings.TrimSpace(q.Get("q")) != "" && len(filter.Tokens) == 0 {
+		http.Error(w, "Recherche trop courte", http.StatusBadRequest)
+		return
+	}
+
+	if v := q.Get("accountId"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Compte invalide", http.StatusBadRequest)
+			return
+		}
+		filter.AccountID = &id
+	}
+	if v := strings.TrimSpace(q.Get("category")); v != "" {
+		filter.Category = &v
+	}
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if v := q.Get(p.name); v != "" {
+			d, err := time.ParseInLocation("2006-01-02", v, time.Local)
+			if err != nil {
+				http.Error(w, "Date invalide", http.StatusBadRequest)
+				return
+			}
+			*p.dst = &d
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  **float64
+	}{{"min", &filter.AmountMin}, {"max", &filter.AmountMax}} {
+		if v := q.Get(p.name); v != "" {
+			amount, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				http.Error(w, "Montant invalide", http.StatusBadRequest)
+				return
+			}
+			*p.dst = &amount
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		filter.Limit, _ = strconv.Atoi(v)
+	}
+
+	txs, err := db.SearchTransactions(user.ID, filter)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	for i := range txs {
+		decryptTransaction(&txs[i])
+	}
+	if txs == nil {
+		txs = []db.Transaction{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(txs)
+}
+
+// ReindexSearch recalcule les mots-cles de toutes les transactions de l'utilisateur
+func ReindexSearch(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	txs, err := db.GetTransactionsByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	for _, t := range txs {
+		decryptTransaction(&t)
+		if err := db.ReplaceTransactionTokens(t.ID, user.ID, transactionTokens(user.ID, t)); err != nil {
+			http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"indexed": len(txs)})
+}
@@ -100,11 +100,18 @@ func DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	files, err := db.GetAttachmentsByUserID(id)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
 	err = db.DeleteUser(id)
 	if err != nil {
 		http.Error(w, "Erreur suppression", http.StatusInternalServerError)
 		return
 	}
+	removeAttachmentFiles(files)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"pilot-finance/internal/crypto"
+	"pilot-finance/internal/db"
+	"pilot-finance/internal/middleware"
+	"pilot-finance/internal/subscriptions"
+)
+
+// SubscriptionsAPI retourne les abonnements detectes dans l'historique avec leur cout
+// annuel, les hausses de prix et les abonnements qui ne sont plus preleves. Une
+// proposition d'operation recurrente s'accepte via le formulaire POST /recurring.
+func SubscriptionsAPI(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	txs, err := db.GetTransactionsByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	for i := range txs {
+		decryptTransaction(&txs[i])
+	}
+
+	recurrings, err := db.GetRecurringByUserID(user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	for i := range recurrings {
+		if decrypted, err := crypto.Decrypt(recurrings[i].Description); err == nil {
+			recurrings[i].Description = decrypted
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(subscriptions.Detect(txs, recurrings, time.Now()))
+}
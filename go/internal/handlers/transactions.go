@@ -0,0 +1,463 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"pilot-finance/internal/crypto"
+	"pilot-finance/internal/db"
+	"pilot-finance/internal/ledger"
+	"pilot-finance/internal/middleware"
+	"pilot-finance/internal/rules"
+)
+
+// decryptTransaction dechiffre le libelle, le tiers et les etiquettes d'une transaction
+func decryptTransaction(t *db.Transaction) {
+	if decrypted, err := crypto.Decrypt(t.Description); err == nil {
+		t.Description = decrypted
+	}
+	if t.Payee != nil {
+		if decrypted, err := crypto.Decrypt(*t.Payee); err == nil {
+			t.Payee = &decrypted
+		}
+	}
+	if t.Tags != nil {
+		if decrypted, err := crypto.Decrypt(*t.Tags); err == nil {
+			t.Tags = &decrypted
+		}
+	}
+	for i := range t.Splits {
+		if t.Splits[i].Memo != nil {
+			if decrypted, err := crypto.Decrypt(*t.Splits[i].Memo); err == nil {
+				t.Splits[i].Memo = &decrypted
+			}
+		}
+	}
+}
+
+// TransactionsAPI retourne les transactions d'un compte en JSON
+func TransactionsAPI(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	acc := requireAccount(w, r, user.ID)
+	if acc == nil {
+		return
+	}
+
+	txs, err := db.GetTransactionsByAccount(acc.ID, user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	for i := range txs {
+		decryptTransaction(&txs[i])
+	}
+	if txs == nil {
+		txs = []db.Transaction{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(txs)
+}
+
+// transactionForm lit les champs d'une transaction saisie manuellement
+func transactionForm(r *http.Request) (rules.Target, time.Time, string) {
+	target := rules.Target{
+		Description: strings.TrimSpace(r.FormValue("description")),
+		Category:    strings.TrimSpace(r.FormValue("category")),
+		Payee:       strings.TrimSpace(r.FormValue("payee")),
+		Tags:        rules.SplitTags(r.FormValue("tags")),
+	}
+
+	amount, err := strconv.ParseFloat(r.FormValue("amount"), 64)
+	if err != nil {
+		return target, time.Time{}, "Montant invalide"
+	}
+	// Ajuster le signe selon le type
+	switch r.FormValue("type") {
+	case "expense":
+		if amount > 0 {
+			amount = -amount
+		}
+	case "income":
+		if amount < 0 {
+			amount = -amount
+		}
+	}
+	target.Amount = amount
+
+	date, err := time.ParseInLocation("2006-01-02", r.FormValue("date"), time.Local)
+	if err != nil {
+		return target, time.Time{}, "Date invalide"
+	}
+
+	if target.Description == "" {
+		return target, date, "Description requise"
+	}
+	return target, date, ""
+}
+
+// saveTransaction applique les regles puis chiffre et enregistre la transaction
+func saveTransaction(w http.ResponseWriter, userID int64, t db.Transaction, target rules.Target) {
+	// Les regles ne completent que les champs laisses vides ; un virement n'a pas de categorie
+	if t.LinkedID != nil {
+		target.Category = ""
+	} else if userRules, err := loadRules(userID); err == nil {
+		rules.Apply(userRules, &target, false)
+	}
+
+	description, err := crypto.Encrypt(target.Description)
+	if err != nil {
+		http.Error(w, "Erreur chiffrement", http.StatusInternalServerError)
+		return
+	}
+	category, payee, tags, err := encryptClassification(target)
+	if err != nil {
+		http.Error(w, "Erreur chiffrement", http.StatusInternalServerError)
+		return
+	}
+
+	t.Amount = target.Amount
+	t.Description = description
+	t.SearchTokens = searchTokens(userID, target.Description, target.Payee, strings.Join(target.Tags, " "))
+	t.Category = category
+	t.Payee = payee
+	t.Tags = tags
+
+	if t.ID == 0 {
+		t.ID, err = db.CreateTransaction(t)
+		if err != nil {
+			http.Error(w, "Erreur creation", http.StatusInternalServerError)
+			return
+		}
+	} else if err := db.UpdateTransaction(t); err != nil {
+		http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+		return
+	}
+
+	saved, err := db.GetTransactionByID(t.ID, userID)
+	if err != nil || saved == nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	decryptTransaction(saved)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// CreateTransaction saisit une transaction sur un compte.
+// Le solde du compte n'est pas modifie, comme pour les imports.
+func CreateTransaction(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	acc := requireAccount(w, r, user.ID)
+	if acc == nil {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	target, date, msg := transactionForm(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	target.AccountID = acc.ID
+
+	saveTransaction(w, user.ID, db.Transaction{UserID: user.ID, AccountID: acc.ID, Date: date}, target)
+}
+
+// UpdateTransaction modifie une transaction
+func UpdateTransaction(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID invalide", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := db.GetTransactionByID(id, user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	if existing == nil {
+		http.Error(w, "Transaction non trouvee", http.StatusNotFound)
+		return
+	}
+	if !checkEditable(w, existing) {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	target, date, msg := transactionForm(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	target.AccountID = existing.AccountID
+	existing.Date = date
+
+	if len(existing.Splits) > 0 && !ledger.ValidateSplits(target.Amount, existing.Splits) {
+		http.Error(w, "Le montant ne correspond plus a la ventilation", http.StatusBadRequest)
+		return
+	}
+
+	saveTransaction(w, user.ID, *existing, target)
+}
+
+// checkEditable refuse la modification d'une transaction rapprochee, ou d'un
+// virement dont l'autre ecriture est rapprochee
+func checkEditable(w http.ResponseWriter, t *db.Transaction) bool {
+	if t.ReconciliationID != nil {
+		http.Error(w, "Transaction deja rapprochee", http.StatusConflict)
+		return false
+	}
+	if t.LinkedID != nil {
+		linked, err := db.GetTransactionByID(*t.LinkedID, t.UserID)
+		if err != nil {
+			http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+			return false
+		}
+		if linked != nil && linked.ReconciliationID != nil {
+			http.Error(w, "Virement deja rapproche sur l'autre compte", http.StatusConflict)
+			return false
+		}
+	}
+	return true
+}
+
+// CreateTransfer enregistre un virement entre deux comptes : un debit sur le
+// compte source et un credit lie sur le compte destination
+func CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	fromID, err := strconv.ParseInt(r.FormValue("fromAccountId"), 10, 64)
+	if err != nil {
+		http.Error(w, "Compte source invalide", http.StatusBadRequest)
+		return
+	}
+	toID, err := strconv.ParseInt(r.FormValue("toAccountId"), 10, 64)
+	if err != nil || toID == fromID {
+		http.Error(w, "Compte destination invalide", http.StatusBadRequest)
+		return
+	}
+	for _, id := range []int64{fromID, toID} {
+		acc, err := db.GetAccountByID(id, user.ID)
+		if err != nil {
+			http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+			return
+		}
+		if acc == nil {
+			http.Error(w, "Compte non trouve", http.StatusNotFound)
+			return
+		}
+	}
+
+	amount, err := strconv.ParseFloat(r.FormValue("amount"), 64)
+	if err != nil || amount <= 0 {
+		http.Error(w, "Montant invalide", http.StatusBadRequest)
+		return
+	}
+	date, err := time.ParseInLocation("2006-01-02", r.FormValue("date"), time.Local)
+	if err != nil {
+		http.Error(w, "Date invalide", http.StatusBadRequest)
+		return
+	}
+
+	description := strings.TrimSpace(r.FormValue("description"))
+	if description == "" {
+		description = "Virement"
+	}
+	encrypted, err := crypto.Encrypt(description)
+	if err != nil {
+		http.Error(w, "Erreur chiffrement", http.StatusInternalServerError)
+		return
+	}
+
+	tokens := searchTokens(user.ID, description)
+	id, err := db.CreateTransfer(
+		db.Transaction{UserID: user.ID, AccountID: fromID, Amount: -amount, Description: encrypted, Date: date, SearchTokens: tokens},
+		db.Transaction{UserID: user.ID, AccountID: toID, Amount: amount, Description: encrypted, Date: date, SearchTokens: tokens},
+	)
+	if err != nil {
+		http.Error(w, "Erreur creation", http.StatusInternalServerError)
+		return
+	}
+
+	saved, err := db.GetTransactionByID(id, user.ID)
+	if err != nil || saved == nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	decryptTransaction(saved)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
+
+// DeleteTransaction supprime une transaction (les deux ecritures pour un virement)
+func DeleteTransaction(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID invalide", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := db.GetTransactionByID(id, user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	if existing == nil {
+		http.Error(w, "Transaction non trouvee", http.StatusNotFound)
+		return
+	}
+	if !checkEditable(w, existing) {
+		return
+	}
+
+	// Fichiers des pieces jointes, supprimees en cascade avec la transaction
+	files, err := db.GetAttachmentsUnderTransaction(id, user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+
+	if err := db.DeleteTransaction(id, user.ID); err != nil {
+		http.Error(w, "Erreur suppression", http.StatusInternalServerError)
+		return
+	}
+	removeAttachmentFiles(files)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// UpdateSplits remplace la ventilation d'une transaction par categorie.
+// Les montants doivent totaliser celui de la transaction ; une liste vide supprime la ventilation.
+func UpdateSplits(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil {
+		http.Error(w, "Non authentifie", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID invalide", http.StatusBadRequest)
+		return
+	}
+
+	existing, err := db.GetTransactionByID(id, user.ID)
+	if err != nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	if existing == nil {
+		http.Error(w, "Transaction non trouvee", http.StatusNotFound)
+		return
+	}
+	if !checkEditable(w, existing) {
+		return
+	}
+
+	if existing.LinkedID != nil {
+		http.Error(w, "Un virement ne se ventile pas", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Donnees invalides", http.StatusBadRequest)
+		return
+	}
+
+	categories := r.Form["category"]
+	amounts := r.Form["amount"]
+	memos := r.Form["memo"]
+	if len(categories) != len(amounts) || (len(memos) != 0 && len(memos) != len(amounts)) {
+		http.Error(w, "Ventilation incomplete", http.StatusBadRequest)
+		return
+	}
+
+	splits := make([]db.TransactionSplit, 0, len(amounts))
+	for i := range amounts {
+		amount, err := strconv.ParseFloat(amounts[i], 64)
+		if err != nil {
+			http.Error(w, "Montant invalide", http.StatusBadRequest)
+			return
+		}
+		split := db.TransactionSplit{Amount: amount}
+		if c := strings.TrimSpace(categories[i]); c != "" {
+			split.Category = &c
+		}
+		if len(memos) > 0 {
+			if split.Memo, err = encryptOptional(strings.TrimSpace(memos[i])); err != nil {
+				http.Error(w, "Erreur chiffrement", http.StatusInternalServerError)
+				return
+			}
+		}
+		splits = append(splits, split)
+	}
+
+	if len(splits) > 0 && !ledger.ValidateSplits(existing.Amount, splits) {
+		http.Error(w, "La ventilation doit totaliser le montant de la transaction", http.StatusBadRequest)
+		return
+	}
+
+	if err := db.ReplaceSplits(id, user.ID, splits); err != nil {
+		http.Error(w, "Erreur mise a jour", http.StatusInternalServerError)
+		return
+	}
+
+	saved, err := db.GetTransactionByID(id, user.ID)
+	if err != nil || saved == nil {
+		http.Error(w, "Erreur serveur", http.StatusInternalServerError)
+		return
+	}
+	decryptTransaction(saved)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(saved)
+}
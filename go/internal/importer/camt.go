@@ -0,0 +1,230 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// camtDocument couvre camt.053 (releve de fin de journee) et camt.052 (releve
+// intrajournalier). Les balises sont lues sans espace de noms pour accepter
+// toutes les versions du schema.
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+	Reports    []camtStatement `xml:"BkToCstmrAcctRpt>Rpt"`
+}
+
+type camtStatement struct {
+	Balances []camtBalance `xml:"Bal"`
+	Entries  []camtEntry   `xml:"Ntry"`
+}
+
+type camtBalance struct {
+	Type      string     `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    camtAmount `xml:"Amt"`
+	Indicator string     `xml:"CdtDbtInd"`
+	Date      camtDate   `xml:"Dt"`
+}
+
+type camtAmount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+// camtStatus est un texte (versions 2 a 7) ou un code (versions 8 et plus)
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+type camtEntry struct {
+	Reference  string          `xml:"NtryRef"`
+	Amount     camtAmount      `xml:"Amt"`
+	Indicator  string          `xml:"CdtDbtInd"`
+	Status     camtStatus      `xml:"Sts"`
+	BookDate   camtDate        `xml:"BookgDt"`
+	ValueDate  camtDate        `xml:"ValDt"`
+	ServicerID string          `xml:"AcctSvcrRef"`
+	Info       string          `xml:"AddtlNtryInf"`
+	Details    []camtTxDetails `xml:"NtryDtls>TxDtls"`
+}
+
+type camtTxDetails struct {
+	EndToEndID   string    `xml:"Refs>EndToEndId"`
+	Debtor       camtParty `xml:"RltdPties>Dbtr"`
+	Creditor     camtParty `xml:"RltdPties>Cdtr"`
+	Unstructured []string  `xml:"RmtInf>Ustrd"`
+	Info         string    `xml:"AddtlTxInf"`
+}
+
+// camtParty lit le nom direct (versions 2 a 7) ou sous Pty (versions 8 et plus)
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	PartyName string `xml:"Pty>Nm"`
+}
+
+func (p camtParty) name() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	return p.PartyName
+}
+
+// ParseCAMT lit un releve ISO 20022 camt.053 ou camt.052. Seules les
+// ecritures comptabilisees (BOOK) sont retenues.
+func ParseCAMT(data []byte) (Statement, error) {
+	var doc camtDocument
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&doc); err != nil {
+		return Statement{}, fmt.Errorf("fichier CAMT invalide : %w", err)
+	}
+
+	statements := append(doc.Statements, doc.Reports...)
+	if len(statements) == 0 {
+		return Statement{}, fmt.Errorf("aucun releve CAMT dans le fichier")
+	}
+
+	var st Statement
+	line := 0
+	for i, s := range statements {
+		for _, e := range s.Entries {
+			line++
+			status := strings.TrimSpace(e.Status.Code)
+			if status == "" {
+				status = strings.TrimSpace(e.Status.Text)
+			}
+			if status != "" && status != "BOOK" {
+				continue
+			}
+
+			row, err := camtRow(e, line)
+			if err != nil {
+				return Statement{}, fmt.Errorf("ecriture %d : %w", line, err)
+			}
+			st.Rows = append(st.Rows, row)
+		}
+
+		// Solde d'ouverture du premier releve, solde de cloture du dernier.
+		// ITBD (solde intrajournalier) sert de cloture pour camt.052.
+		var closing *camtBalance
+		for j, b := range s.Balances {
+			switch b.Type {
+			case "OPBD", "PRCD":
+				if i == 0 && st.OpeningBalance == nil {
+					amount, err := camtSigned(b.Amount.Value, b.Indicator)
+					if err != nil {
+						return Statement{}, fmt.Errorf("solde : %w", err)
+					}
+					st.OpeningBalance = &amount
+				}
+			case "CLBD":
+				closing = &s.Balances[j]
+			case "ITBD":
+				if closing == nil || closing.Type != "CLBD" {
+					closing = &s.Balances[j]
+				}
+			}
+		}
+		if closing != nil {
+			amount, err := camtSigned(closing.Amount.Value, closing.Indicator)
+			if err != nil {
+				return Statement{}, fmt.Errorf("solde : %w", err)
+			}
+			st.ClosingBalance = &amount
+			st.BalanceDate = nil
+			if date, err := closing.Date.parse(); err == nil {
+				st.BalanceDate = &date
+			}
+		}
+	}
+
+	if len(st.Rows) == 0 && st.ClosingBalance == nil {
+		return Statement{}, ErrEmpty
+	}
+	return st, nil
+}
+
+func camtRow(e camtEntry, line int) (Row, error) {
+	amount, err := camtSigned(e.Amount.Value, e.Indicator)
+	if err != nil {
+		return Row{}, err
+	}
+
+	date, err := e.BookDate.parse()
+	if err != nil {
+		if date, err = e.ValueDate.parse(); err != nil {
+			return Row{}, fmt.Errorf("date manquante")
+		}
+	}
+
+	row := Row{Line: line, Date: date, Amount: amount}
+
+	// Contrepartie : le creancier pour un debit, le debiteur pour un credit
+	var remittance []string
+	for _, d := range e.Details {
+		if row.Counterparty == "" {
+			if amount < 0 {
+				row.Counterparty = strings.TrimSpace(d.Creditor.name())
+			} else {
+				row.Counterparty = strings.TrimSpace(d.Debtor.name())
+			}
+		}
+		remittance = append(remittance, d.Unstructured...)
+		if d.Info != "" {
+			remittance = append(remittance, d.Info)
+		}
+	}
+	if len(remittance) == 0 && e.Info != "" {
+		remittance = append(remittance, e.Info)
+	}
+
+	parts := remittance
+	if row.Counterparty != "" {
+		parts = append([]string{row.Counterparty}, remittance...)
+	}
+	row.Description = strings.Join(strings.Fields(strings.Join(parts, " ")), " ")
+
+	// Reference banque stable pour ignorer les doublons
+	switch {
+	case e.ServicerID != "":
+		row.Reference = e.ServicerID
+	case e.Reference != "":
+		row.Reference = e.Reference
+	case len(e.Details) > 0 && e.Details[0].EndToEndID != "" && e.Details[0].EndToEndID != "NOTPROVIDED":
+		row.Reference = e.Details[0].EndToEndID
+	}
+
+	return row, nil
+}
+
+// camtSigned applique le sens (CRDT/DBIT) a un montant toujours positif en CAMT
+func camtSigned(value, indicator string) (float64, error) {
+	amount, err := ParseAmount(value, false)
+	if err != nil {
+		return 0, err
+	}
+	if amount < 0 {
+		amount = -amount
+	}
+	if strings.TrimSpace(indicator) == "DBIT" {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+func (d camtDate) parse() (time.Time, error) {
+	if d.Date != "" {
+		return ParseDate(d.Date, "YYYY-MM-DD")
+	}
+	if len(d.DateTime) >= 10 {
+		return ParseDate(d.DateTime[:10], "YYYY-MM-DD")
+	}
+	return time.Time{}, fmt.Errorf("date manquante")
+}
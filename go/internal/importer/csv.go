@@ -0,0 +1,217 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strings"
+)
+
+// CSVMapping decrit la structure d'un export CSV bancaire. Les colonnes sont
+// numerotees a partir de 1, 0 signifiant absente.
+type CSVMapping struct {
+	Separator          string `json:"separator"`    // ";" par defaut si detecte
+	Encoding           string `json:"encoding"`     // UTF-8, ISO-8859-1, WINDOWS-1252
+	DecimalComma       bool   `json:"decimalComma"` // "1 234,56"
+	DateFormat         string `json:"dateFormat"`   // DD/MM/YYYY par defaut
+	SkipRows           int    `json:"skipRows"`     // Lignes de preambule avant l'en-tete
+	HasHeader          bool   `json:"hasHeader"`
+	DateColumn         int    `json:"dateColumn"`
+	AmountColumn       int    `json:"amountColumn"`
+	DebitColumn        int    `json:"debitColumn"` // Si debit et credit sont separes
+	CreditColumn       int    `json:"creditColumn"`
+	DescriptionColumns []int  `json:"descriptionColumns"`
+	ReferenceColumn    int    `json:"referenceColumn"`
+}
+
+// Validate verifie que la correspondance permet de lire date et montant
+func (m CSVMapping) Validate() error {
+	if m.DateColumn <= 0 {
+		return fmt.Errorf("colonne de date requise")
+	}
+	if m.AmountColumn <= 0 && m.DebitColumn <= 0 && m.CreditColumn <= 0 {
+		return fmt.Errorf("colonne de montant requise")
+	}
+	return nil
+}
+
+// CSVPreview contient l'en-tete et les premieres lignes brutes d'un fichier,
+// pour aider a definir la correspondance des colonnes
+type CSVPreview struct {
+	Separator string     `json:"separator"`
+	Header    []string   `json:"header"`
+	Lines     [][]string `json:"lines"`
+}
+
+// PreviewCSV lit les premieres lignes d'un fichier sans correspondance
+func PreviewCSV(data []byte, m CSVMapping, limit int) (CSVPreview, error) {
+	records, sep, err := readCSV(data, m)
+	if err != nil {
+		return CSVPreview{}, err
+	}
+	p := CSVPreview{Separator: string(sep)}
+	if m.HasHeader && len(records) > 0 {
+		p.Header = records[0]
+		records = records[1:]
+	}
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	p.Lines = records
+	return p, nil
+}
+
+// ParseCSV lit un releve CSV selon la correspondance fournie
+func ParseCSV(data []byte, m CSVMapping) (Statement, error) {
+	if err := m.Validate(); err != nil {
+		return Statement{}, err
+	}
+	records, _, err := readCSV(data, m)
+	if err != nil {
+		return Statement{}, err
+	}
+
+	first := m.SkipRows + 1
+	if m.HasHeader && len(records) > 0 {
+		records = records[1:]
+		first++
+	}
+
+	var st Statement
+	for i, rec := range records {
+		line := first + i
+		if isBlank(rec) {
+			continue
+		}
+
+		date, err := ParseDate(field(rec, m.DateColumn), m.DateFormat)
+		if err != nil {
+			return Statement{}, fmt.Errorf("ligne %d : %w", line, err)
+		}
+
+		amount, err := rowAmount(rec, m)
+		if err != nil {
+			return Statement{}, fmt.Errorf("ligne %d : %w", line, err)
+		}
+
+		var parts []string
+		for _, c := range m.DescriptionColumns {
+			if v := strings.TrimSpace(field(rec, c)); v != "" {
+				parts = append(parts, v)
+			}
+		}
+
+		st.Rows = append(st.Rows, Row{
+			Line:        line,
+			Date:        date,
+			Amount:      amount,
+			Description: strings.Join(strings.Fields(strings.Join(parts, " ")), " "),
+			Reference:   strings.TrimSpace(field(rec, m.ReferenceColumn)),
+		})
+	}
+
+	if len(st.Rows) == 0 {
+		return Statement{}, ErrEmpty
+	}
+	return st, nil
+}
+
+// rowAmount lit le montant signe, depuis une colonne unique ou debit/credit
+func rowAmount(rec []string, m CSVMapping) (float64, error) {
+	if m.AmountColumn > 0 {
+		return ParseAmount(field(rec, m.AmountColumn), m.DecimalComma)
+	}
+
+	amount := 0.0
+	if v := strings.TrimSpace(field(rec, m.CreditColumn)); v != "" {
+		credit, err := ParseAmount(v, m.DecimalComma)
+		if err != nil {
+			return 0, err
+		}
+		amount += abs(credit)
+	}
+	if v := strings.TrimSpace(field(rec, m.DebitColumn)); v != "" {
+		debit, err := ParseAmount(v, m.DecimalComma)
+		if err != nil {
+			return 0, err
+		}
+		amount -= abs(debit)
+	}
+	return amount, nil
+}
+
+func readCSV(data []byte, m CSVMapping) ([][]string, rune, error) {
+	text, err := Decode(data, m.Encoding)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if m.SkipRows > 0 {
+		if m.SkipRows >= len(lines) {
+			return nil, 0, ErrEmpty
+		}
+		lines = lines[m.SkipRows:]
+	}
+	body := strings.Join(lines, "\n")
+
+	sep := detectSeparator(lines, m.Separator)
+	reader := csv.NewReader(strings.NewReader(body))
+	reader.Comma = sep
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, 0, fmt.Errorf("CSV invalide : %w", err)
+	}
+	return records, sep, nil
+}
+
+// detectSeparator retient le separateur explicite ou le plus frequent de la premiere ligne
+func detectSeparator(lines []string, explicit string) rune {
+	switch explicit {
+	case "\\t", "tab":
+		return '\t'
+	case "":
+	default:
+		return []rune(explicit)[0]
+	}
+
+	first := ""
+	for _, l := range lines {
+		if strings.TrimSpace(l) != "" {
+			first = l
+			break
+		}
+	}
+	best, count := ';', 0
+	for _, c := range []rune{';', ',', '\t', '|'} {
+		if n := strings.Count(first, string(c)); n > count {
+			best, count = c, n
+		}
+	}
+	return best
+}
+
+func field(rec []string, col int) string {
+	if col <= 0 || col > len(rec) {
+		return ""
+	}
+	return rec[col-1]
+}
+
+func isBlank(rec []string) bool {
+	for _, v := range rec {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
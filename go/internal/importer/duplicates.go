@@ -0,0 +1,95 @@
+package importer
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"pilot-finance/internal/textnorm"
+)
+
+// LikelyDuplicateDays est l'ecart maximal entre dates pour un doublon probable
+// (date d'operation / de valeur)
+const LikelyDuplicateDays = 3
+
+// Existing est une transaction deja enregistree sur le compte, candidate au
+// rapprochement, avec ses empreintes (vides si absentes)
+type Existing struct {
+	ID          int64
+	Amount      float64
+	Date        time.Time
+	Fingerprint string
+	ExternalRef string
+}
+
+// FingerprintKey retourne le texte dont l'empreinte identifie une operation : la
+// reference banque si elle existe, sinon la date, le montant et le libelle normalise
+func FingerprintKey(accountID int64, row Row) string {
+	if row.Reference != "" {
+		return fmt.Sprintf("fp:%d:ref:%s", accountID, row.Reference)
+	}
+	return fmt.Sprintf("fp:%d:%s:%d:%s", accountID,
+		row.Date.Format("2006-01-02"), int64(math.Round(row.Amount*100)), textnorm.Normalize(row.Description))
+}
+
+// DuplicateWindow retourne la periode de l'historique a comparer aux lignes du releve
+func DuplicateWindow(rows []Row) (time.Time, time.Time) {
+	from, to := rows[0].Date, rows[0].Date
+	for _, row := range rows {
+		if row.Date.Before(from) {
+			from = row.Date
+		}
+		if row.Date.After(to) {
+			to = row.Date
+		}
+	}
+	window := LikelyDuplicateDays * 24 * time.Hour
+	return from.Add(-window), to.Add(window + 24*time.Hour)
+}
+
+// MarkDuplicates rapproche les lignes du releve des transactions existantes et
+// retourne le nombre de doublons. fingerprint et externalRef calculent les
+// empreintes d'une ligne (externalRef vide sans reference banque). Chaque transaction
+// existante ne correspond qu'a une seule ligne, ce qui laisse importer deux
+// operations identiques le meme jour.
+func MarkDuplicates(rows []Row, existing []Existing, fingerprint, externalRef func(Row) string) int {
+	claimed := make(map[int64]bool)
+	claim := func(i int, id int64, match string) {
+		claimed[id] = true
+		rows[i].Match = match
+		rows[i].DuplicateOf = id
+	}
+
+	// Correspondances exactes d'abord, pour ne pas les perdre au profit d'un rapprochement approximatif
+	for i, row := range rows {
+		fp := fingerprint(row)
+		ref := externalRef(row)
+		for _, t := range existing {
+			if claimed[t.ID] {
+				continue
+			}
+			if (t.Fingerprint != "" && t.Fingerprint == fp) || (ref != "" && t.ExternalRef == ref) {
+				claim(i, t.ID, MatchExact)
+				break
+			}
+		}
+	}
+
+	window := (LikelyDuplicateDays + 1) * 24 * time.Hour
+	for i, row := range rows {
+		if row.Match != "" {
+			continue
+		}
+		for _, t := range existing {
+			if claimed[t.ID] || math.Abs(t.Amount-row.Amount) >= 0.005 {
+				continue
+			}
+			if gap := t.Date.Sub(row.Date); gap > -window && gap < window {
+				claim(i, t.ID, MatchLikely)
+				break
+			}
+		}
+	}
+
+	return len(claimed)
+}
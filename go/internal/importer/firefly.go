@@ -0,0 +1,161 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// fireflySplit est une operation Firefly III (ligne du CSV ou element de l'API JSON)
+type fireflySplit struct {
+	Type            string `json:"type"`
+	Date            string `json:"date"`
+	Amount          string `json:"amount"`
+	Description     string `json:"description"`
+	SourceName      string `json:"source_name"`
+	SourceType      string `json:"source_type"`
+	DestinationName string `json:"destination_name"`
+	DestinationType string `json:"destination_type"`
+	Category        string `json:"category_name"`
+}
+
+// ParseFireflyCSV lit l'export CSV des transactions de Firefly III
+func ParseFireflyCSV(data []byte) (Migration, error) {
+	t, err := readCSVTable(data)
+	if err != nil {
+		return Migration{}, err
+	}
+	if !t.has("type") || !t.has("amount") || !t.has("source_name") {
+		return Migration{}, fmt.Errorf("export Firefly III non reconnu")
+	}
+
+	b := newMigrationBuilder(SourceFireflyCSV)
+	for _, rec := range t.records {
+		split := fireflySplit{
+			Type:            t.get(rec, "type"),
+			Date:            t.get(rec, "date"),
+			Amount:          t.get(rec, "amount"),
+			Description:     t.get(rec, "description"),
+			SourceName:      t.get(rec, "source_name"),
+			SourceType:      t.get(rec, "source_type"),
+			DestinationName: t.get(rec, "destination_name"),
+			DestinationType: t.get(rec, "destination_type"),
+			Category:        t.get(rec, "category"),
+		}
+		if err := b.addFirefly(split); err != nil {
+			return Migration{}, err
+		}
+	}
+	return b.result()
+}
+
+// ParseFireflyJSON lit la reponse de l'API Firefly III (/api/v1/transactions
+// et /api/v1/recurrences, separement ou concatenees dans un tableau "data")
+func ParseFireflyJSON(data []byte) (Migration, error) {
+	var doc struct {
+		Data []struct {
+			Attributes struct {
+				Transactions []fireflySplit `json:"transactions"`
+				Title        string         `json:"title"`
+				Repetitions  []struct {
+					Type   string `json:"type"`
+					Moment string `json:"moment"`
+					Skip   int    `json:"skip"`
+				} `json:"repetitions"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return Migration{}, fmt.Errorf("JSON Firefly III invalide : %w", err)
+	}
+
+	b := newMigrationBuilder(SourceFireflyJSON)
+	for _, item := range doc.Data {
+		attrs := item.Attributes
+
+		// Recurrence : seules les repetitions mensuelles sont reprises
+		if len(attrs.Repetitions) > 0 {
+			rep := attrs.Repetitions[0]
+			day, _ := strconv.Atoi(rep.Moment)
+			if rep.Type != "monthly" || rep.Skip > 0 || day < 1 || len(attrs.Transactions) == 0 {
+				b.warn("Recurrence ignoree (non mensuelle) : %s", attrs.Title)
+				continue
+			}
+			b.m.Recurrings = append(b.m.Recurrings, fireflyRecurring(attrs.Title, day, attrs.Transactions[0]))
+			continue
+		}
+
+		for _, split := range attrs.Transactions {
+			if err := b.addFirefly(split); err != nil {
+				return Migration{}, err
+			}
+		}
+	}
+
+	return b.result()
+}
+
+// isFireflyAsset indique un compte d'actif Firefly, seuls migres en comptes Pilot
+func isFireflyAsset(accountType string) bool {
+	switch strings.ToLower(accountType) {
+	case "asset account", "default account", "asset":
+		return true
+	}
+	return false
+}
+
+func (b *migrationBuilder) addFirefly(s fireflySplit) error {
+	if len(s.Date) < 10 {
+		return fmt.Errorf("date Firefly III invalide : %s", s.Date)
+	}
+	date, err := ParseDate(s.Date[:10], "YYYY-MM-DD")
+	if err != nil {
+		return err
+	}
+	amount, err := parseLooseAmount(s.Amount)
+	if err != nil {
+		return err
+	}
+	amount = abs(amount)
+
+	row := Row{Date: date, Description: s.Description, Category: s.Category}
+
+	// Le montant sort de la source et entre dans la destination
+	handled := false
+	if isFireflyAsset(s.SourceType) {
+		out := row
+		out.Amount = -amount
+		b.add(s.SourceName, out)
+		handled = true
+	}
+	if isFireflyAsset(s.DestinationType) {
+		in := row
+		in.Amount = amount
+		b.add(s.DestinationName, in)
+		handled = true
+	}
+	if !handled {
+		b.warn("Operation sans compte d'actif ignoree : %s (%s)", s.Description, s.Type)
+	}
+	return nil
+}
+
+func fireflyRecurring(title string, day int, s fireflySplit) MigratedRecurring {
+	amount, _ := parseLooseAmount(s.Amount)
+	amount = abs(amount)
+
+	rec := MigratedRecurring{Description: title, DayOfMonth: day}
+	if s.Description != "" {
+		rec.Description = s.Description
+	}
+	switch {
+	case isFireflyAsset(s.SourceType) && isFireflyAsset(s.DestinationType):
+		rec.Account, rec.ToAccount, rec.Amount = s.SourceName, s.DestinationName, amount
+	case isFireflyAsset(s.DestinationType):
+		rec.Account, rec.Amount = s.DestinationName, amount
+	default:
+		rec.Account, rec.Amount = s.SourceName, -amount
+	}
+	return rec
+}
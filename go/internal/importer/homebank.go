@@ -0,0 +1,147 @@
+package importer
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type xhbFile struct {
+	Accounts   []xhbAccount   `xml:"account"`
+	Payees     []xhbPayee     `xml:"pay"`
+	Categories []xhbCategory  `xml:"cat"`
+	Favorites  []xhbOperation `xml:"fav"`
+	Operations []xhbOperation `xml:"ope"`
+}
+
+type xhbAccount struct {
+	Key     int     `xml:"key,attr"`
+	Name    string  `xml:"name,attr"`
+	Initial float64 `xml:"initial,attr"`
+}
+
+type xhbPayee struct {
+	Key  int    `xml:"key,attr"`
+	Name string `xml:"name,attr"`
+}
+
+type xhbCategory struct {
+	Key    int    `xml:"key,attr"`
+	Parent int    `xml:"parent,attr"`
+	Name   string `xml:"name,attr"`
+}
+
+// xhbOperation couvre les operations (ope) et les operations planifiees (fav)
+type xhbOperation struct {
+	Date       int64   `xml:"date,attr"`
+	NextDate   int64   `xml:"nextdate,attr"`
+	Amount     float64 `xml:"amount,attr"`
+	Account    int     `xml:"account,attr"`
+	DstAccount int     `xml:"dst_account,attr"`
+	Payee      int     `xml:"payee,attr"`
+	Category   int     `xml:"category,attr"`
+	Wording    string  `xml:"wording,attr"`
+	Memo       string  `xml:"memo,attr"` // Nom de "wording" depuis HomeBank 5.6
+	Info       string  `xml:"info,attr"`
+	Every      int     `xml:"every,attr"`
+	Unit       int     `xml:"unit,attr"` // 0 jour, 1 semaine, 2 mois, 3 annee
+}
+
+// Unite mensuelle des operations planifiees HomeBank
+const xhbUnitMonth = 2
+
+// ParseHomebank lit un fichier HomeBank (.xhb)
+func ParseHomebank(data []byte) (Migration, error) {
+	var f xhbFile
+	if err := xml.Unmarshal(data, &f); err != nil {
+		return Migration{}, fmt.Errorf("fichier HomeBank invalide : %w", err)
+	}
+
+	accounts := map[int]string{}
+	payees := map[int]string{}
+	for _, p := range f.Payees {
+		payees[p.Key] = p.Name
+	}
+	categories := xhbCategoryNames(f.Categories)
+
+	b := newMigrationBuilder(SourceHomebank)
+	for _, a := range f.Accounts {
+		accounts[a.Key] = a.Name
+		b.account(a.Name).Initial = a.Initial
+	}
+
+	for _, op := range f.Operations {
+		name, ok := accounts[op.Account]
+		if !ok {
+			b.warn("Operation sur un compte inconnu ignoree : %s", op.memo())
+			continue
+		}
+		payee := payees[op.Payee]
+		b.add(name, Row{
+			Date:         xhbDate(op.Date),
+			Amount:       op.Amount,
+			Counterparty: payee,
+			Description:  strings.Join(strings.Fields(payee+" "+op.memo()), " "),
+			Category:     categories[op.Category],
+		})
+	}
+
+	for _, fav := range f.Favorites {
+		if fav.NextDate == 0 {
+			continue // Modele sans planification
+		}
+		if fav.Unit != xhbUnitMonth || fav.Every != 1 {
+			b.warn("Operation planifiee ignoree (non mensuelle) : %s", fav.memo())
+			continue
+		}
+		rec := MigratedRecurring{
+			Account:     accounts[fav.Account],
+			Description: fav.memo(),
+			Amount:      fav.Amount,
+			DayOfMonth:  xhbDate(fav.NextDate).Day(),
+		}
+		if rec.Description == "" {
+			rec.Description = payees[fav.Payee]
+		}
+		if fav.DstAccount > 0 {
+			rec.ToAccount = accounts[fav.DstAccount]
+			rec.Amount = abs(fav.Amount)
+		}
+		b.m.Recurrings = append(b.m.Recurrings, rec)
+	}
+
+	return b.result()
+}
+
+func (op xhbOperation) memo() string {
+	if op.Memo != "" {
+		return op.Memo
+	}
+	if op.Wording != "" {
+		return op.Wording
+	}
+	return op.Info
+}
+
+// xhbCategoryNames construit les noms complets "Parent:Sous-categorie"
+func xhbCategoryNames(cats []xhbCategory) map[int]string {
+	byKey := map[int]xhbCategory{}
+	for _, c := range cats {
+		byKey[c.Key] = c
+	}
+	names := map[int]string{}
+	for _, c := range cats {
+		name := c.Name
+		if parent, ok := byKey[c.Parent]; ok && c.Parent != 0 {
+			name = parent.Name + ":" + name
+		}
+		names[c.Key] = name
+	}
+	return names
+}
+
+// xhbDate convertit une date julienne GLib (1 = 1er janvier de l'an 1)
+func xhbDate(julian int64) time.Time {
+	return time.Date(1, 1, 1, 0, 0, 0, 0, time.Local).AddDate(0, 0, int(julian-1))
+}
@@ -0,0 +1,170 @@
+// Package importer convertit les releves bancaires (CSV, OFX, CAMT, QIF) en lignes de transactions.
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrEmpty est retournee quand le fichier ne contient aucune operation
+var ErrEmpty = errors.New("aucune operation dans le fichier")
+
+// Row est une operation lue dans un releve
+type Row struct {
+	Line         int       `json:"line"`
+	Date         time.Time `json:"date"`
+	Amount       float64   `json:"amount"`
+	Description  string    `json:"description"`
+	Counterparty string    `json:"counterparty,omitempty"` // Tiers (CAMT, QIF)
+	Category     string    `json:"category,omitempty"`
+	Reference    string    `json:"reference,omitempty"`   // Identifiant de la banque (FITID, ...)
+	Match        string    `json:"match,omitempty"`       // Doublon probable : MatchExact ou MatchLikely
+	DuplicateOf  int64     `json:"duplicateOf,omitempty"` // Transaction existante correspondante
+}
+
+// Niveaux de correspondance avec une transaction existante
+const (
+	MatchExact  = "exact"  // Meme empreinte (reference banque ou date, montant et libelle)
+	MatchLikely = "likely" // Meme montant a quelques jours d'ecart
+)
+
+// Statement regroupe les operations d'un releve importe
+type Statement struct {
+	Rows           []Row      `json:"rows"`
+	OpeningBalance *float64   `json:"openingBalance,omitempty"` // Solde de debut donne par la banque
+	ClosingBalance *float64   `json:"closingBalance,omitempty"` // Solde de fin donne par la banque
+	BalanceDate    *time.Time `json:"balanceDate,omitempty"`
+}
+
+// Total retourne la somme des montants du releve
+func (s Statement) Total() float64 {
+	total := 0.0
+	for _, r := range s.Rows {
+		total += r.Amount
+	}
+	return total
+}
+
+// Encodages supportes pour les fichiers texte
+const (
+	EncodingUTF8   = "UTF-8"
+	EncodingLatin1 = "ISO-8859-1"
+	EncodingCP1252 = "WINDOWS-1252"
+)
+
+// cp1252 couvre les caracteres 0x80-0x9F qui different de l'ISO-8859-1
+var cp1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+// Decode convertit le contenu d'un fichier en UTF-8. Sans encodage explicite,
+// un fichier qui n'est pas de l'UTF-8 valide est lu en Windows-1252, comme
+// les exports des banques francaises.
+func Decode(data []byte, encoding string) (string, error) {
+	switch strings.ToUpper(encoding) {
+	case "", EncodingUTF8, "UTF8":
+		text := strings.TrimPrefix(string(data), "\ufeff")
+		if utf8.ValidString(text) {
+			return text, nil
+		}
+		if encoding != "" {
+			return "", errors.New("fichier non UTF-8")
+		}
+		return decodeSingleByte(data, true), nil
+	case EncodingLatin1, "LATIN1", "LATIN-1":
+		return decodeSingleByte(data, false), nil
+	case EncodingCP1252, "CP1252":
+		return decodeSingleByte(data, true), nil
+	}
+	return "", fmt.Errorf("encodage inconnu : %s", encoding)
+}
+
+func decodeSingleByte(data []byte, windows bool) string {
+	var b strings.Builder
+	b.Grow(len(data))
+	for _, c := range data {
+		if windows && c >= 0x80 && c < 0xA0 {
+			b.WriteRune(cp1252[c-0x80])
+			continue
+		}
+		b.WriteRune(rune(c))
+	}
+	return b.String()
+}
+
+// ParseAmount lit un montant au format francais ("-1 234,56 €") ou anglais ("-1,234.56")
+func ParseAmount(s string, decimalComma bool) (float64, error) {
+	s = strings.TrimSpace(s)
+	negative := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		negative = true
+		s = s[1 : len(s)-1]
+	}
+
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9', r == '-', r == '+':
+			b.WriteRune(r)
+		case r == ',':
+			if decimalComma {
+				b.WriteRune('.')
+			}
+		case r == '.':
+			if !decimalComma {
+				b.WriteRune('.')
+			}
+		}
+		// Espaces, espaces insecables, apostrophes et symboles monetaires ignores
+	}
+
+	clean := b.String()
+	if clean == "" {
+		return 0, fmt.Errorf("montant vide")
+	}
+	amount, err := strconv.ParseFloat(clean, 64)
+	if err != nil {
+		return 0, fmt.Errorf("montant invalide : %s", s)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// dateLayouts associe les formats lisibles aux formats Go
+var dateLayouts = map[string]string{
+	"DD/MM/YYYY": "02/01/2006",
+	"DD/MM/YY":   "02/01/06",
+	"DD-MM-YYYY": "02-01-2006",
+	"DD.MM.YYYY": "02.01.2006",
+	"YYYY-MM-DD": "2006-01-02",
+	"YYYYMMDD":   "20060102",
+	"MM/DD/YYYY": "01/02/2006",
+}
+
+// ParseDate lit une date selon un format lisible (DD/MM/YYYY, ...) ou un format Go
+func ParseDate(s, format string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if format == "" {
+		format = "DD/MM/YYYY"
+	}
+	layout, ok := dateLayouts[strings.ToUpper(format)]
+	if !ok {
+		layout = format
+	}
+	// Certaines banques ajoutent l'heure apres la date
+	if len(s) > len(layout) {
+		s = s[:len(layout)]
+	}
+	t, err := time.ParseInLocation(layout, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("date invalide : %s", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,286 @@
+package importer
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseCSVFrenchBank(t *testing.T) {
+	// Export Windows-1252 : preambule, en-tete, debit/credit separes
+	data := []byte("Compte courant;12345\r\n" +
+		"Date;Libell\xe9;D\xe9bit;Cr\xe9dit\r\n" +
+		"03/01/2024;CB Caf\xe9 ;12,50;\r\n" +
+		"05/01/2024;VIR SALAIRE;;2 345,67\r\n" +
+		";;;\r\n")
+
+	st, err := ParseCSV(data, CSVMapping{
+		DecimalComma:       true,
+		SkipRows:           1,
+		HasHeader:          true,
+		DateColumn:         1,
+		DebitColumn:        3,
+		CreditColumn:       4,
+		DescriptionColumns: []int{2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Rows) != 2 {
+		t.Fatalf("rows = %d, want 2", len(st.Rows))
+	}
+
+	first := st.Rows[0]
+	if first.Amount != -12.5 || first.Description != "CB Café" || first.Line != 3 {
+		t.Errorf("first row = %+v", first)
+	}
+	if first.Date.Day() != 3 || first.Date.Month() != 1 {
+		t.Errorf("date = %v", first.Date)
+	}
+	if math.Abs(st.Total()-2333.17) > 1e-9 {
+		t.Errorf("total = %v", st.Total())
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	cases := []struct {
+		in    string
+		comma bool
+		want  float64
+	}{
+		{"-1 234,56 €", true, -1234.56},
+		{"1.234,56", true, 1234.56},
+		{"-1,234.56", false, -1234.56},
+		{"(42.00)", false, -42},
+		{"+7", false, 7},
+	}
+	for _, c := range cases {
+		got, err := ParseAmount(c.in, c.comma)
+		if err != nil || got != c.want {
+			t.Errorf("ParseAmount(%q) = %v, %v; want %v", c.in, got, err, c.want)
+		}
+	}
+}
+
+func TestParseOFXSGML(t *testing.T) {
+	data := []byte(`OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+CHARSET:1252
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240103120000.000[+1:CET]
+<TRNAMT>-12,50
+<FITID>ABC1
+<NAME>CB CAFE
+<MEMO>PARIS
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240105
+<TRNAMT>2345.67
+<FITID>ABC2
+<NAME>VIR SALAIRE &amp; PRIME
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>1500.00<DTASOF>20240131</LEDGERBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>`)
+
+	st, err := ParseOFX(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Rows) != 2 {
+		t.Fatalf("rows = %d, want 2", len(st.Rows))
+	}
+	if r := st.Rows[0]; r.Amount != -12.5 || r.Reference != "ABC1" || r.Description != "CB CAFE PARIS" || r.Date.Day() != 3 {
+		t.Errorf("first row = %+v", r)
+	}
+	if r := st.Rows[1]; r.Description != "VIR SALAIRE & PRIME" {
+		t.Errorf("second row = %+v", r)
+	}
+	if st.ClosingBalance == nil || *st.ClosingBalance != 1500 {
+		t.Errorf("closing balance = %v", st.ClosingBalance)
+	}
+}
+
+func TestParseOFXXML(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX><CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20240210</DTPOSTED><TRNAMT>-42.00</TRNAMT><FITID>X9</FITID><NAME>Librairie</NAME><MEMO></MEMO></STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>-42.00</BALAMT><DTASOF>20240229</DTASOF></LEDGERBAL>
+</CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1></OFX>`)
+
+	st, err := ParseOFX(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Rows) != 1 || st.Rows[0].Amount != -42 || st.Rows[0].Reference != "X9" || st.Rows[0].Description != "Librairie" {
+		t.Fatalf("rows = %+v", st.Rows)
+	}
+	if st.ClosingBalance == nil || *st.ClosingBalance != -42 {
+		t.Errorf("closing balance = %v", st.ClosingBalance)
+	}
+}
+
+func TestParseCAMT053(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">
+<BkToCstmrStmt><Stmt>
+<Bal><Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp><Amt Ccy="EUR">100.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2024-03-01</Dt></Dt></Bal>
+<Bal><Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp><Amt Ccy="EUR">60.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2024-03-02</Dt></Dt></Bal>
+<Ntry><Amt Ccy="EUR">40.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts><Cd>BOOK</Cd></Sts>
+<BookgDt><Dt>2024-03-02</Dt></BookgDt><AcctSvcrRef>REF-1</AcctSvcrRef>
+<NtryDtls><TxDtls><RltdPties><Cdtr><Pty><Nm>EDF</Nm></Pty></Cdtr></RltdPties><RmtInf><Ustrd>Facture mars</Ustrd></RmtInf></TxDtls></NtryDtls></Ntry>
+<Ntry><Amt Ccy="EUR">5.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Sts><Cd>PDNG</Cd></Sts><BookgDt><Dt>2024-03-02</Dt></BookgDt></Ntry>
+</Stmt></BkToCstmrStmt>
+</Document>`)
+
+	st, err := ParseCAMT(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Rows) != 1 {
+		t.Fatalf("rows = %d, want 1 (pending entry skipped)", len(st.Rows))
+	}
+	r := st.Rows[0]
+	if r.Amount != -40 || r.Counterparty != "EDF" || r.Description != "EDF Facture mars" || r.Reference != "REF-1" {
+		t.Errorf("row = %+v", r)
+	}
+	if st.OpeningBalance == nil || *st.OpeningBalance != 100 || st.ClosingBalance == nil || *st.ClosingBalance != 60 {
+		t.Errorf("balances = %v, %v", st.OpeningBalance, st.ClosingBalance)
+	}
+}
+
+func TestQIFRoundTrip(t *testing.T) {
+	data := []byte("!Account\nNCourant\nTBank\n^\n!Type:Bank\n" +
+		"D3/ 1'24\nT-12.50\nPBoulangerie\nMCroissants\nLAlimentation:Pain\n^\n" +
+		"D05/01/2024\nT100.00\nPVirement\nL[Livret A]\n^\n")
+
+	st, err := ParseQIF(data, "", map[string]string{"Alimentation:Pain": "Courses"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Rows) != 2 {
+		t.Fatalf("rows = %d, want 2", len(st.Rows))
+	}
+	r := st.Rows[0]
+	if r.Amount != -12.5 || r.Description != "Boulangerie Croissants" || r.Category != "Courses" || r.Date.Day() != 3 || r.Date.Year() != 2024 {
+		t.Errorf("first row = %+v", r)
+	}
+	if st.Rows[1].Category != "" {
+		t.Errorf("transfer category = %q", st.Rows[1].Category)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteQIF(&buf, st.Rows); err != nil {
+		t.Fatal(err)
+	}
+	again, err := ParseQIF(buf.Bytes(), "DD/MM/YYYY", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Rows) != 2 || again.Rows[0].Amount != -12.5 || again.Rows[0].Category != "Courses" || !again.Rows[0].Date.Equal(r.Date) {
+		t.Errorf("round trip = %+v", again.Rows)
+	}
+}
+
+func TestParseHomebank(t *testing.T) {
+	// 738900 = 2024-01-15 en date julienne GLib
+	data := []byte(`<?xml version="1.0"?>
+<homebank v="1.4">
+<account key="1" name="Courant" initial="100"/>
+<account key="2" name="Livret" initial="0"/>
+<pay key="1" name="Boulangerie"/>
+<cat key="1" name="Alimentation"/>
+<cat key="2" parent="1" name="Pain"/>
+<fav amount="50" account="1" dst_account="2" wording="Epargne" nextdate="738900" every="1" unit="2"/>
+<ope date="738900" amount="-2.5" account="1" payee="1" category="2" wording="Baguette"/>
+</homebank>`)
+
+	m, err := ParseHomebank(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Accounts) != 2 || m.Accounts[0].Balance() != 97.5 {
+		t.Fatalf("accounts = %+v", m.Accounts)
+	}
+	r := m.Accounts[0].Rows[0]
+	if r.Category != "Alimentation:Pain" || r.Description != "Boulangerie Baguette" || r.Date.Format("2006-01-02") != "2024-01-15" {
+		t.Errorf("row = %+v", r)
+	}
+	if len(m.Recurrings) != 1 || m.Recurrings[0].ToAccount != "Livret" || m.Recurrings[0].DayOfMonth != 15 {
+		t.Errorf("recurrings = %+v", m.Recurrings)
+	}
+}
+
+func TestFingerprintKey(t *testing.T) {
+	day := time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)
+	base := Row{Date: day, Amount: -12.5, Description: "CB Café  - Paris"}
+
+	tests := []struct {
+		name string
+		a, b Row
+		acc  int64
+		same bool
+	}{
+		{"libelle normalise", base, Row{Date: day, Amount: -12.5, Description: "cb cafe paris"}, 1, true},
+		{"montant different", base, Row{Date: day, Amount: -12.51, Description: "CB Café  - Paris"}, 1, false},
+		{"date differente", base, Row{Date: day.AddDate(0, 0, 1), Amount: -12.5, Description: "CB Café  - Paris"}, 1, false},
+		{"reference prioritaire", Row{Reference: "F1", Date: day, Amount: -1}, Row{Reference: "F1", Date: day.AddDate(0, 0, 2), Amount: -2}, 1, true},
+		{"autre compte", base, base, 2, false},
+	}
+	for _, tt := range tests {
+		same := FingerprintKey(1, tt.a) == FingerprintKey(tt.acc, tt.b)
+		if same != tt.same {
+			t.Errorf("%s: meme empreinte = %v, want %v", tt.name, same, tt.same)
+		}
+	}
+}
+
+func TestMarkDuplicates(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 3, d, 0, 0, 0, 0, time.UTC) }
+	fp := func(r Row) string { return FingerprintKey(1, r) }
+	ref := func(r Row) string { return r.Reference }
+
+	rows := []Row{
+		{Date: day(4), Amount: -12.5, Description: "Cafe"},   // Meme empreinte
+		{Date: day(4), Amount: -12.5, Description: "Cafe"},   // Deuxieme cafe le meme jour
+		{Date: day(5), Amount: -80, Reference: "F9"},         // Meme reference banque
+		{Date: day(10), Amount: -42, Description: "Essence"}, // Meme montant 3 jours avant
+		{Date: day(20), Amount: -15, Description: "Cinema"},  // Meme montant hors fenetre
+	}
+	existing := []Existing{
+		{ID: 1, Date: day(4), Amount: -12.5, Fingerprint: fp(rows[0])},
+		{ID: 2, Date: day(6), Amount: -80, ExternalRef: "F9"},
+		{ID: 3, Date: day(7), Amount: -42},
+		{ID: 4, Date: day(12), Amount: -15},
+	}
+
+	if n := MarkDuplicates(rows, existing, fp, ref); n != 3 {
+		t.Errorf("doublons = %d, want 3", n)
+	}
+	want := []struct {
+		match string
+		of    int64
+	}{{MatchExact, 1}, {"", 0}, {MatchExact, 2}, {MatchLikely, 3}, {"", 0}}
+	for i, w := range want {
+		if rows[i].Match != w.match || rows[i].DuplicateOf != w.of {
+			t.Errorf("ligne %d = %q/%d, want %q/%d", i, rows[i].Match, rows[i].DuplicateOf, w.match, w.of)
+		}
+	}
+
+	from, to := DuplicateWindow(rows)
+	if !from.Equal(day(1)) || !to.Equal(day(24)) {
+		t.Errorf("fenetre = %s -> %s", from, to)
+	}
+}
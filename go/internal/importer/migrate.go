@@ -0,0 +1,190 @@
+package importer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sources de migration depuis d'autres logiciels de finances personnelles
+const (
+	SourceFireflyCSV  = "firefly-csv"
+	SourceFireflyJSON = "firefly-json"
+	SourceYNAB        = "ynab"
+	SourceHomebank    = "homebank"
+)
+
+// Migration est le contenu complet extrait d'un export d'un autre logiciel
+type Migration struct {
+	Source     string              `json:"source"`
+	Accounts   []MigratedAccount   `json:"accounts"`
+	Recurrings []MigratedRecurring `json:"recurrings"`
+	Warnings   []string            `json:"warnings"`
+}
+
+// MigratedAccount est un compte a creer avec son historique
+type MigratedAccount struct {
+	Name    string  `json:"name"`
+	Initial float64 `json:"initial"` // Solde avant la premiere operation
+	Rows    []Row   `json:"-"`
+}
+
+// Balance retourne le solde du compte apres toutes ses operations
+func (a MigratedAccount) Balance() float64 {
+	balance := a.Initial
+	for _, r := range a.Rows {
+		balance += r.Amount
+	}
+	return balance
+}
+
+// MigratedRecurring est une operation mensuelle a recreer
+type MigratedRecurring struct {
+	Account     string  `json:"account"`
+	ToAccount   string  `json:"toAccount,omitempty"` // Virement vers un autre compte migre
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+	DayOfMonth  int     `json:"dayOfMonth"`
+}
+
+// MigrationReport resume une migration avant enregistrement (dry-run)
+type MigrationReport struct {
+	Source     string                 `json:"source"`
+	Accounts   []MigrationAccountLine `json:"accounts"`
+	Categories []string               `json:"categories"`
+	Recurrings []MigratedRecurring    `json:"recurrings"`
+	Warnings   []string               `json:"warnings"`
+}
+
+// MigrationAccountLine resume un compte migre
+type MigrationAccountLine struct {
+	Name         string     `json:"name"`
+	Balance      float64    `json:"balance"`
+	Transactions int        `json:"transactions"`
+	From         *time.Time `json:"from,omitempty"`
+	To           *time.Time `json:"to,omitempty"`
+}
+
+// Report construit le resume d'une migration
+func (m Migration) Report() MigrationReport {
+	report := MigrationReport{
+		Source:     m.Source,
+		Accounts:   []MigrationAccountLine{},
+		Categories: []string{},
+		Recurrings: m.Recurrings,
+		Warnings:   m.Warnings,
+	}
+	if report.Recurrings == nil {
+		report.Recurrings = []MigratedRecurring{}
+	}
+	if report.Warnings == nil {
+		report.Warnings = []string{}
+	}
+
+	categories := map[string]bool{}
+	for _, a := range m.Accounts {
+		line := MigrationAccountLine{Name: a.Name, Balance: a.Balance(), Transactions: len(a.Rows)}
+		for i := range a.Rows {
+			d := a.Rows[i].Date
+			if line.From == nil || d.Before(*line.From) {
+				line.From = &a.Rows[i].Date
+			}
+			if line.To == nil || d.After(*line.To) {
+				line.To = &a.Rows[i].Date
+			}
+			if c := a.Rows[i].Category; c != "" {
+				categories[c] = true
+			}
+		}
+		report.Accounts = append(report.Accounts, line)
+	}
+	for c := range categories {
+		report.Categories = append(report.Categories, c)
+	}
+	sort.Strings(report.Categories)
+	return report
+}
+
+// migrationBuilder regroupe les operations par compte en gardant l'ordre d'apparition
+type migrationBuilder struct {
+	m     Migration
+	index map[string]int
+}
+
+func newMigrationBuilder(source string) *migrationBuilder {
+	return &migrationBuilder{m: Migration{Source: source}, index: map[string]int{}}
+}
+
+func (b *migrationBuilder) account(name string) *MigratedAccount {
+	name = strings.TrimSpace(name)
+	i, ok := b.index[name]
+	if !ok {
+		i = len(b.m.Accounts)
+		b.index[name] = i
+		b.m.Accounts = append(b.m.Accounts, MigratedAccount{Name: name})
+	}
+	return &b.m.Accounts[i]
+}
+
+func (b *migrationBuilder) add(account string, row Row) {
+	acc := b.account(account)
+	row.Line = len(acc.Rows) + 1
+	acc.Rows = append(acc.Rows, row)
+}
+
+func (b *migrationBuilder) warn(format string, args ...interface{}) {
+	b.m.Warnings = append(b.m.Warnings, fmt.Sprintf(format, args...))
+}
+
+func (b *migrationBuilder) result() (Migration, error) {
+	if len(b.m.Accounts) == 0 && len(b.m.Recurrings) == 0 {
+		return Migration{}, ErrEmpty
+	}
+	// Historique chronologique par compte
+	for i := range b.m.Accounts {
+		rows := b.m.Accounts[i].Rows
+		sort.SliceStable(rows, func(x, y int) bool { return rows[x].Date.Before(rows[y].Date) })
+	}
+	return b.m, nil
+}
+
+// csvTable lit un CSV avec en-tete et donne acces aux colonnes par nom
+type csvTable struct {
+	columns map[string]int
+	records [][]string
+}
+
+func readCSVTable(data []byte) (*csvTable, error) {
+	records, _, err := readCSV(data, CSVMapping{})
+	if err != nil {
+		return nil, err
+	}
+	if len(records) < 2 {
+		return nil, ErrEmpty
+	}
+
+	t := &csvTable{columns: map[string]int{}, records: records[1:]}
+	for i, name := range records[0] {
+		t.columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	return t, nil
+}
+
+func (t *csvTable) has(name string) bool {
+	_, ok := t.columns[name]
+	return ok
+}
+
+func (t *csvTable) get(rec []string, name string) string {
+	i, ok := t.columns[name]
+	if !ok || i >= len(rec) {
+		return ""
+	}
+	return strings.TrimSpace(rec[i])
+}
+
+// parseLooseAmount devine le separateur decimal : le dernier de "," ou "." l'emporte
+func parseLooseAmount(s string) (float64, error) {
+	return ParseAmount(s, strings.LastIndex(s, ",") > strings.LastIndex(s, "."))
+}
@@ -0,0 +1,159 @@
+package importer
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+// ParseOFX lit un releve OFX 1.x (SGML, balises non fermees) ou 2.x (XML).
+// Les releves bancaires (STMTRS) et de carte (CCSTMTRS) sont acceptes.
+func ParseOFX(data []byte) (Statement, error) {
+	text, err := Decode(data, "")
+	if err != nil {
+		return Statement{}, err
+	}
+
+	start := strings.Index(strings.ToUpper(text), "<OFX>")
+	if start < 0 {
+		return Statement{}, fmt.Errorf("fichier OFX invalide")
+	}
+	text = text[start:]
+
+	var st Statement
+	var current *Row
+	var stack []string
+	var ledger struct {
+		amount string
+		date   string
+	}
+	line := 0
+
+	for len(text) > 0 {
+		open := strings.IndexByte(text, '<')
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(text[open:], '>')
+		if end < 0 {
+			break
+		}
+		tag := strings.ToUpper(strings.TrimSpace(text[open+1 : open+end]))
+		text = text[open+end+1:]
+
+		// Valeur texte jusqu'a la balise suivante
+		next := strings.IndexByte(text, '<')
+		if next < 0 {
+			next = len(text)
+		}
+		value := strings.TrimSpace(html.UnescapeString(text[:next]))
+
+		if strings.HasPrefix(tag, "/") {
+			name := tag[1:]
+			if name == "STMTTRN" && current != nil {
+				st.Rows = append(st.Rows, *current)
+				current = nil
+			}
+			// En SGML, les feuilles ne sont pas fermees : on depile jusqu'a l'agregat
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i] == name {
+					stack = stack[:i]
+					break
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+			continue
+		}
+
+		if value == "" {
+			// Agregat (balise ouvrante sans valeur)
+			stack = append(stack, tag)
+			if tag == "STMTTRN" {
+				line++
+				current = &Row{Line: line}
+			}
+			continue
+		}
+
+		switch {
+		case current != nil:
+			if err := setOFXField(current, tag, value); err != nil {
+				return Statement{}, fmt.Errorf("operation %d : %w", current.Line, err)
+			}
+		case inAggregate(stack, "LEDGERBAL"):
+			switch tag {
+			case "BALAMT":
+				ledger.amount = value
+			case "DTASOF":
+				ledger.date = value
+			}
+		}
+	}
+
+	if ledger.amount != "" {
+		amount, err := parseOFXAmount(ledger.amount)
+		if err != nil {
+			return Statement{}, fmt.Errorf("solde : %w", err)
+		}
+		st.ClosingBalance = &amount
+		if date, err := parseOFXDate(ledger.date); err == nil {
+			st.BalanceDate = &date
+		}
+	}
+
+	if len(st.Rows) == 0 && st.ClosingBalance == nil {
+		return Statement{}, ErrEmpty
+	}
+	return st, nil
+}
+
+func setOFXField(row *Row, tag, value string) error {
+	switch tag {
+	case "DTPOSTED":
+		date, err := parseOFXDate(value)
+		if err != nil {
+			return err
+		}
+		row.Date = date
+	case "TRNAMT":
+		amount, err := parseOFXAmount(value)
+		if err != nil {
+			return err
+		}
+		row.Amount = amount
+	case "FITID":
+		row.Reference = value
+	case "NAME", "PAYEE":
+		row.Description = strings.TrimSpace(value + " " + row.Description)
+	case "MEMO":
+		if !strings.Contains(row.Description, value) {
+			row.Description = strings.TrimSpace(row.Description + " " + value)
+		}
+	}
+	return nil
+}
+
+// parseOFXDate lit une date OFX (YYYYMMDD[HHMMSS[.XXX][TZ]]), seul le jour compte
+func parseOFXDate(s string) (time.Time, error) {
+	if len(s) < 8 {
+		return time.Time{}, fmt.Errorf("date invalide : %s", s)
+	}
+	return ParseDate(s[:8], "YYYYMMDD")
+}
+
+// parseOFXAmount accepte le point ou la virgule decimale (certaines banques francaises)
+func parseOFXAmount(s string) (float64, error) {
+	return ParseAmount(s, strings.Contains(s, ",") && !strings.Contains(s, "."))
+}
+
+func inAggregate(stack []string, name string) bool {
+	for _, s := range stack {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseQIF lit un fichier QIF (Quicken, GnuCash, Grisbi, Homebank).
+// dateFormat indique l'ordre jour/mois (DD/MM/YYYY par defaut, ou MM/DD/YYYY) ;
+// categories renomme les categories du fichier, les autres sont conservees.
+func ParseQIF(data []byte, dateFormat string, categories map[string]string) (Statement, error) {
+	text, err := Decode(data, "")
+	if err != nil {
+		return Statement{}, err
+	}
+
+	var st Statement
+	var row Row
+	var payee, memo string
+	started := false
+	skip := false
+	line := 0
+	record := 1
+
+	flush := func() error {
+		if !started {
+			return nil
+		}
+		if row.Date.IsZero() {
+			return fmt.Errorf("operation %d : date manquante", record)
+		}
+		row.Line = record
+		row.Counterparty = payee
+		row.Description = strings.Join(strings.Fields(payee+" "+memo), " ")
+		if mapped, ok := categories[row.Category]; ok {
+			row.Category = mapped
+		}
+		st.Rows = append(st.Rows, row)
+		row, payee, memo, started = Row{}, "", "", false
+		record++
+		return nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line++
+		l := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+
+		code, value := l[0], strings.TrimSpace(l[1:])
+		switch code {
+		case '!':
+			// En-tete de section : seules les sections d'operations sont lues
+			// (pas !Account, !Type:Cat, !Type:Invst, ...)
+			skip = !isQIFRegister(value)
+			continue
+		case '^':
+			if skip {
+				continue
+			}
+			if err := flush(); err != nil {
+				return Statement{}, err
+			}
+			continue
+		}
+		if skip {
+			continue
+		}
+
+		started = true
+		switch code {
+		case 'D':
+			date, err := parseQIFDate(value, dateFormat)
+			if err != nil {
+				return Statement{}, fmt.Errorf("ligne %d : %w", line, err)
+			}
+			row.Date = date
+		case 'T', 'U':
+			amount, err := ParseAmount(value, false)
+			if err != nil {
+				return Statement{}, fmt.Errorf("ligne %d : %w", line, err)
+			}
+			row.Amount = amount
+		case 'P':
+			payee = value
+		case 'M':
+			memo = value
+		case 'L':
+			// [Compte] designe un virement, pas une categorie
+			if !strings.HasPrefix(value, "[") {
+				row.Category = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Statement{}, err
+	}
+	if err := flush(); err != nil {
+		return Statement{}, err
+	}
+
+	if len(st.Rows) == 0 {
+		return Statement{}, ErrEmpty
+	}
+	return st, nil
+}
+
+func isQIFRegister(header string) bool {
+	switch strings.ToUpper(strings.TrimSpace(header)) {
+	case "TYPE:BANK", "TYPE:CASH", "TYPE:CCARD", "TYPE:OTH A", "TYPE:OTH L":
+		return true
+	}
+	return false
+}
+
+// parseQIFDate lit les variantes de dates QIF : 03/01/2024, 3/ 1'24, 2024-01-03
+func parseQIFDate(s, format string) (time.Time, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '/' || r == '-' || r == '.' || r == '\'' || r == ' '
+	})
+	if len(fields) != 3 {
+		return time.Time{}, fmt.Errorf("date invalide : %s", s)
+	}
+
+	var nums [3]int
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("date invalide : %s", s)
+		}
+		nums[i] = n
+	}
+
+	var day, month, year int
+	switch {
+	case len(fields[0]) == 4:
+		year, month, day = nums[0], nums[1], nums[2]
+	case strings.HasPrefix(strings.ToUpper(format), "MM"):
+		month, day, year = nums[0], nums[1], nums[2]
+	default:
+		day, month, year = nums[0], nums[1], nums[2]
+	}
+	if year < 100 {
+		if year < 70 {
+			year += 2000
+		} else {
+			year += 1900
+		}
+	}
+	if month < 1 || month > 12 || day < 1 || day > 31 {
+		return time.Time{}, fmt.Errorf("date invalide : %s", s)
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local), nil
+}
+
+// WriteQIF exporte des operations au format QIF bancaire, dates en DD/MM/YYYY
+func WriteQIF(w io.Writer, rows []Row) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "!Type:Bank")
+	for _, r := range rows {
+		fmt.Fprintf(bw, "D%s\n", r.Date.Format("02/01/2006"))
+		fmt.Fprintf(bw, "T%.2f\n", r.Amount)
+		if r.Description != "" {
+			fmt.Fprintf(bw, "P%s\n", qifValue(r.Description))
+		}
+		if r.Category != "" {
+			fmt.Fprintf(bw, "L%s\n", qifValue(r.Category))
+		}
+		fmt.Fprintln(bw, "^")
+	}
+	return bw.Flush()
+}
+
+// qifValue retire les retours a la ligne qui casseraient le format
+func qifValue(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,62 @@
+package importer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseYNAB lit l'export "register" de YNAB. Le format de date depend des
+// reglages du budget (DD/MM/YYYY par defaut).
+func ParseYNAB(data []byte, dateFormat string) (Migration, error) {
+	t, err := readCSVTable(data)
+	if err != nil {
+		return Migration{}, err
+	}
+	if !t.has("account") || !t.has("outflow") || !t.has("inflow") {
+		return Migration{}, fmt.Errorf("export YNAB non reconnu")
+	}
+
+	b := newMigrationBuilder(SourceYNAB)
+	for i, rec := range t.records {
+		date, err := ParseDate(t.get(rec, "date"), dateFormat)
+		if err != nil {
+			return Migration{}, fmt.Errorf("ligne %d : %w", i+2, err)
+		}
+
+		amount := 0.0
+		if v := t.get(rec, "inflow"); v != "" {
+			inflow, err := parseLooseAmount(v)
+			if err != nil {
+				return Migration{}, fmt.Errorf("ligne %d : %w", i+2, err)
+			}
+			amount += abs(inflow)
+		}
+		if v := t.get(rec, "outflow"); v != "" {
+			outflow, err := parseLooseAmount(v)
+			if err != nil {
+				return Migration{}, fmt.Errorf("ligne %d : %w", i+2, err)
+			}
+			amount -= abs(outflow)
+		}
+
+		payee := t.get(rec, "payee")
+		category := t.get(rec, "category")
+		if category == "" {
+			category = t.get(rec, "category group/category")
+		}
+		// Les virements et revenus a repartir n'ont pas de categorie de depense
+		if strings.HasPrefix(payee, "Transfer :") || strings.Contains(category, "Ready to Assign") ||
+			strings.Contains(category, "To be Budgeted") {
+			category = ""
+		}
+
+		b.add(t.get(rec, "account"), Row{
+			Date:         date,
+			Amount:       amount,
+			Counterparty: payee,
+			Description:  strings.Join(strings.Fields(payee+" "+t.get(rec, "memo")), " "),
+			Category:     category,
+		})
+	}
+	return b.result()
+}
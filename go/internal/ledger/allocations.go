@@ -0,0 +1,43 @@
+package ledger
+
+import "pilot-finance/internal/db"
+
+// Allocation est la part d'un revenu viree vers un compte
+type Allocation struct {
+	ToAccountID int64
+	Amount      float64
+}
+
+// ValidateAllocations verifie que chaque regle porte un pourcentage positif et que
+// l'ensemble ne depasse pas le revenu
+func ValidateAllocations(rules []db.AllocationRule) bool {
+	sum := 0.0
+	for _, r := range rules {
+		if r.Percent <= 0 || r.Percent > 100 {
+			return false
+		}
+		sum += r.Percent
+	}
+	return sum <= 100+epsilon
+}
+
+// AllocateIncome repartit un revenu selon les regles, dans leur ordre. Les parts sont
+// arrondies au centime sur les pourcentages cumules, pour que leur somme ne depasse
+// jamais le revenu ; le reste demeure sur le compte qui l'a recu.
+func AllocateIncome(amount float64, rules []db.AllocationRule) []Allocation {
+	if amount <= 0 {
+		return nil
+	}
+	var allocations []Allocation
+	var cumulated, allocated float64
+	for _, r := range rules {
+		cumulated += r.Percent
+		share := round(amount*cumulated/100) - allocated
+		if share <= 0 {
+			continue
+		}
+		allocated += share
+		allocations = append(allocations, Allocation{ToAccountID: r.ToAccountID, Amount: round(share)})
+	}
+	return allocations
+}
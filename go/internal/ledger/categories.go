@@ -0,0 +1,97 @@
+package ledger
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"pilot-finance/internal/db"
+)
+
+// Part est la part d'une transaction imputee a une categorie
+type Part struct {
+	Category string // Vide si non categorisee
+	Amount   float64
+}
+
+// Parts decompose une transaction par categorie : ses ventilations si elle en a,
+// sinon la transaction entiere dans sa categorie
+func Parts(t db.Transaction) []Part {
+	if len(t.Splits) == 0 {
+		return []Part{{Category: deref(t.Category), Amount: t.Amount}}
+	}
+	parts := make([]Part, 0, len(t.Splits))
+	for _, s := range t.Splits {
+		parts = append(parts, Part{Category: deref(s.Category), Amount: s.Amount})
+	}
+	return parts
+}
+
+// CategoryTotal est le total d'une categorie sur une periode
+type CategoryTotal struct {
+	Category string  `json:"category"`
+	Income   float64 `json:"income"`
+	Expenses float64 `json:"expenses"` // Positif
+	Net      float64 `json:"net"`
+	Count    int     `json:"count"`
+}
+
+// IsTransfer indique une ecriture de virement entre comptes, ni revenu ni depense
+func IsTransfer(t db.Transaction) bool {
+	return t.LinkedID != nil
+}
+
+// CategoryTotals totalise les transactions par categorie entre deux dates
+// incluses, ventilations comprises, hors virements. Les categories sont triees par depenses.
+func CategoryTotals(txs []db.Transaction, from, to time.Time) []CategoryTotal {
+	byCategory := make(map[string]*CategoryTotal)
+	for _, t := range txs {
+		if t.Date.Before(from) || t.Date.After(to) || IsTransfer(t) {
+			continue
+		}
+		for _, p := range Parts(t) {
+			total, ok := byCategory[p.Category]
+			if !ok {
+				total = &CategoryTotal{Category: p.Category}
+				byCategory[p.Category] = total
+			}
+			if p.Amount >= 0 {
+				total.Income += p.Amount
+			} else {
+				total.Expenses -= p.Amount
+			}
+			total.Count++
+		}
+	}
+
+	totals := make([]CategoryTotal, 0, len(byCategory))
+	for _, total := range byCategory {
+		total.Income = round(total.Income)
+		total.Expenses = round(total.Expenses)
+		total.Net = round(total.Income - total.Expenses)
+		totals = append(totals, *total)
+	}
+	sort.Slice(totals, func(i, j int) bool {
+		if totals[i].Expenses != totals[j].Expenses {
+			return totals[i].Expenses > totals[j].Expenses
+		}
+		return totals[i].Category < totals[j].Category
+	})
+	return totals
+}
+
+// ValidateSplits verifie que les ventilations couvrent exactement le montant
+func ValidateSplits(amount float64, splits []db.TransactionSplit) bool {
+	sum := 0.0
+	for _, s := range splits {
+		sum += s.Amount
+	}
+	return math.Abs(sum-amount) < epsilon
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
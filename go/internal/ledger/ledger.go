@@ -0,0 +1,69 @@
+// Package ledger calcule les soldes et totaux derives de l'historique des transactions.
+package ledger
+
+import (
+	"math"
+	"time"
+
+	"pilot-finance/internal/db"
+)
+
+// Tolerance des comparaisons de montants (arrondis au centime)
+const epsilon = 0.005
+
+// Balance retourne la somme des transactions jusqu'a une date incluse
+// (zero pour toutes les transactions)
+func Balance(txs []db.Transaction, until time.Time) float64 {
+	total := 0.0
+	for _, t := range txs {
+		if until.IsZero() || !t.Date.After(until) {
+			total += t.Amount
+		}
+	}
+	return round(total)
+}
+
+// Reconciliation est l'etat d'un rapprochement en cours
+type Reconciliation struct {
+	StatementDate    time.Time        `json:"statementDate"`
+	StatementBalance float64          `json:"statementBalance"`
+	OpeningBalance   float64          `json:"openingBalance"` // Solde du dernier point de controle
+	ClearedBalance   float64          `json:"clearedBalance"` // Ouverture + transactions pointees
+	LedgerBalance    float64          `json:"ledgerBalance"`  // Ouverture + toutes les transactions
+	Difference       float64          `json:"difference"`     // Releve - solde pointe
+	Balanced         bool             `json:"balanced"`
+	Pending          []db.Transaction `json:"pending"` // Transactions a pointer jusqu'a la date
+}
+
+// Reconcile compare le solde d'un releve au solde pointe : solde d'ouverture
+// plus les transactions pointees et non encore rapprochees jusqu'a la date
+func Reconcile(opening, statementBalance float64, statementDate time.Time, txs []db.Transaction) Reconciliation {
+	rec := Reconciliation{
+		StatementDate:    statementDate,
+		StatementBalance: statementBalance,
+		OpeningBalance:   opening,
+		Pending:          []db.Transaction{},
+	}
+
+	cleared, ledger := opening, opening
+	for _, t := range txs {
+		if t.ReconciliationID != nil || t.Date.After(statementDate) {
+			continue
+		}
+		rec.Pending = append(rec.Pending, t)
+		ledger += t.Amount
+		if t.Cleared {
+			cleared += t.Amount
+		}
+	}
+
+	rec.ClearedBalance = round(cleared)
+	rec.LedgerBalance = round(ledger)
+	rec.Difference = round(statementBalance - cleared)
+	rec.Balanced = math.Abs(rec.Difference) < epsilon
+	return rec
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
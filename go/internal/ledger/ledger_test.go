@@ -0,0 +1,112 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"pilot-finance/internal/db"
+)
+
+func TestReconcile(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	checkpoint := int64(1)
+	txs := []db.Transaction{
+		{ID: 1, Amount: -20, Date: day(1), Cleared: true, ReconciliationID: &checkpoint},
+		{ID: 2, Amount: -12.3, Date: day(5), Cleared: true},
+		{ID: 3, Amount: 100, Date: day(6)},
+		{ID: 4, Amount: -5, Date: day(20), Cleared: true},
+	}
+
+	rec := Reconcile(500, 587.7, day(10), txs)
+	if len(rec.Pending) != 2 || rec.ClearedBalance != 487.7 || rec.Difference != 100 || rec.Balanced {
+		t.Fatalf("rec = %+v", rec)
+	}
+
+	txs[2].Cleared = true
+	rec = Reconcile(500, 587.7, day(10), txs)
+	if !rec.Balanced || rec.Difference != 0 {
+		t.Errorf("rec = %+v", rec)
+	}
+}
+
+func TestCategoryTotalsUseSplitsAndSkipTransfers(t *testing.T) {
+	food, home, salary := "Courses", "Maison", "Salaire"
+	linked := int64(6)
+	day := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	txs := []db.Transaction{
+		{ID: 1, Amount: -80, Date: day, Category: &food, Splits: []db.TransactionSplit{
+			{Category: &food, Amount: -50},
+			{Category: &home, Amount: -30},
+		}},
+		{ID: 2, Amount: -10, Date: day, Category: &food},
+		{ID: 3, Amount: 2000, Date: day, Category: &salary},
+		{ID: 4, Amount: -99, Date: day.AddDate(0, 1, 0), Category: &home},
+		{ID: 5, Amount: -500, Date: day, LinkedID: &linked},
+	}
+
+	totals := CategoryTotals(txs, day.AddDate(0, 0, -4), day.AddDate(0, 0, 20))
+	want := map[string]float64{"Courses": 60, "Maison": 30, "Salaire": 0}
+	if len(totals) != 3 {
+		t.Fatalf("totals = %+v", totals)
+	}
+	for _, total := range totals {
+		if total.Expenses != want[total.Category] {
+			t.Errorf("%s expenses = %v, want %v", total.Category, total.Expenses, want[total.Category])
+		}
+	}
+	if !ValidateSplits(-80, txs[0].Splits) || ValidateSplits(-81, txs[0].Splits) {
+		t.Error("ValidateSplits")
+	}
+}
+
+func TestPockets(t *testing.T) {
+	pockets := []db.Pocket{{Amount: 5000}, {Amount: 1200.5}, {Amount: 800}}
+	if !ValidatePockets(7000.5, pockets) || ValidatePockets(7000, pockets) {
+		t.Error("ValidatePockets")
+	}
+	if got := Unallocated(7100.5, pockets); got != 100 {
+		t.Errorf("Unallocated = %v", got)
+	}
+	if got := Unallocated(300, nil); got != 0 {
+		t.Errorf("Unallocated sans poche = %v", got)
+	}
+
+	// Solde passe sous le total des poches : repartition au prorata
+	if got := ScalePockets(7000.5, pockets); got[0].Amount != 5000 {
+		t.Errorf("ScalePockets sans depassement = %+v", got)
+	}
+	scaled := ScalePockets(3500.25, pockets)
+	if scaled[0].Amount != 2500 || scaled[1].Amount != 600.25 || scaled[2].Amount != 400 || pockets[0].Amount != 5000 {
+		t.Errorf("ScalePockets = %+v", scaled)
+	}
+	if got := ScalePockets(-50, pockets); got[1].Amount != 0 {
+		t.Errorf("ScalePockets solde negatif = %+v", got)
+	}
+}
+
+func TestAllocateIncome(t *testing.T) {
+	rules := []db.AllocationRule{{ToAccountID: 2, Percent: 20}, {ToAccountID: 3, Percent: 10}}
+	if !ValidateAllocations(rules) {
+		t.Fatal("ValidateAllocations")
+	}
+	got := AllocateIncome(2345.67, rules)
+	if len(got) != 2 || got[0] != (Allocation{2, 469.13}) || got[1] != (Allocation{3, 234.57}) {
+		t.Errorf("AllocateIncome = %+v", got)
+	}
+
+	thirds := []db.AllocationRule{{ToAccountID: 2, Percent: 100.0 / 3}, {ToAccountID: 3, Percent: 100.0 / 3}, {ToAccountID: 4, Percent: 100.0 / 3}}
+	sum := 0.0
+	for _, a := range AllocateIncome(100, thirds) {
+		sum += a.Amount
+	}
+	if round(sum) != 100 {
+		t.Errorf("somme des tiers = %v", sum)
+	}
+
+	if ValidateAllocations(append(rules, db.AllocationRule{ToAccountID: 4, Percent: 75})) {
+		t.Error("plus de 100% accepte")
+	}
+	if AllocateIncome(-50, rules) != nil {
+		t.Error("depense repartie")
+	}
+}
@@ -0,0 +1,42 @@
+package ledger
+
+import (
+	"math"
+
+	"pilot-finance/internal/db"
+)
+
+// ValidatePockets verifie que les poches repartissent exactement le solde du compte
+func ValidatePockets(balance float64, pockets []db.Pocket) bool {
+	return math.Abs(Unallocated(balance, pockets)) < epsilon
+}
+
+// Unallocated retourne la part du solde qui n'est dans aucune poche : elle apparait
+// quand le solde evolue apres la repartition (negative si les poches le depassent)
+func Unallocated(balance float64, pockets []db.Pocket) float64 {
+	if len(pockets) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, p := range pockets {
+		sum += p.Amount
+	}
+	return round(balance - sum)
+}
+
+// ScalePockets ramene les poches au solde du compte quand elles le depassent, au
+// prorata de leurs montants (a zero si le solde est negatif) ; sinon elles sont
+// retournees telles quelles
+func ScalePockets(balance float64, pockets []db.Pocket) []db.Pocket {
+	rest := Unallocated(balance, pockets)
+	if rest >= 0 {
+		return pockets
+	}
+	ratio := math.Max(balance, 0) / (balance - rest)
+	scaled := make([]db.Pocket, len(pockets))
+	for i, p := range pockets {
+		scaled[i] = p
+		scaled[i].Amount = round(p.Amount * ratio)
+	}
+	return scaled
+}
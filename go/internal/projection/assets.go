@@ -0,0 +1,72 @@
+package projection
+
+import (
+	"math"
+	"time"
+
+	"pilot-finance/internal/db"
+)
+
+// isValuedAsset indique si le compte est un bien avec une regle de valorisation
+func isValuedAsset(acc *db.Account) bool {
+	return acc.Kind == "ASSET" && (acc.ValuationRule == "ANNUAL_RATE" || acc.ValuationRule == "LINEAR")
+}
+
+// monthlyDepreciation retourne la decote mensuelle d'un bien amorti lineairement
+func monthlyDepreciation(acc *db.Account) float64 {
+	if acc.DepreciationYears <= 0 {
+		return 0
+	}
+	base := acc.PurchasePrice
+	if base <= 0 {
+		base = acc.Balance
+	}
+	return base / float64(acc.DepreciationYears*12)
+}
+
+// valueAsset fait evoluer la valeur d'un bien d'un mois, date etant le mois simule
+func valueAsset(acc *db.Account, balance float64, date time.Time) float64 {
+	switch acc.ValuationRule {
+	case "ANNUAL_RATE":
+		return balance * math.Pow(1+acc.ValuationRate/100, 1.0/12)
+	case "LINEAR":
+		// Plus de decote une fois la duree d'amortissement ecoulee
+		if acc.PurchaseDate != nil && !date.Before(acc.PurchaseDate.AddDate(acc.DepreciationYears, 0, 0)) {
+			return balance
+		}
+		return math.Max(0, balance-monthlyDepreciation(acc))
+	}
+	return balance
+}
+
+// AssetValueAt calcule la valeur theorique d'un bien a une date a partir
+// de son prix et de sa date d'achat. Retourne false si le bien n'est pas valorisable.
+func AssetValueAt(acc db.Account, at time.Time) (float64, bool) {
+	if !isValuedAsset(&acc) || acc.PurchasePrice <= 0 || acc.PurchaseDate == nil {
+		return 0, false
+	}
+
+	months := monthsBetween(*acc.PurchaseDate, at)
+	if months < 0 {
+		return acc.PurchasePrice, true
+	}
+
+	switch acc.ValuationRule {
+	case "ANNUAL_RATE":
+		return acc.PurchasePrice * math.Pow(1+acc.ValuationRate/100, float64(months)/12), true
+	default:
+		if acc.DepreciationYears <= 0 {
+			return acc.PurchasePrice, true
+		}
+		return math.Max(0, acc.PurchasePrice-monthlyDepreciation(&acc)*float64(months)), true
+	}
+}
+
+// monthsBetween retourne le nombre de mois entiers entre deux dates
+func monthsBetween(from, to time.Time) int {
+	months := (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+	if to.Day() < from.Day() {
+		months--
+	}
+	return months
+}
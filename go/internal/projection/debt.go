@@ -0,0 +1,247 @@
+package projection
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"pilot-finance/internal/db"
+)
+
+// Strategies de remboursement des dettes
+const (
+	StrategyAvalanche = "AVALANCHE" // Taux le plus eleve d'abord
+	StrategySnowball  = "SNOWBALL"  // Plus petit capital d'abord
+	StrategyCustom    = "CUSTOM"    // Ordre choisi par l'utilisateur
+)
+
+// maxPayoffMonths borne la simulation d'un plan de remboursement (50 ans)
+const maxPayoffMonths = 600
+
+// ErrPayoffNotReached indique des mensualites qui ne couvrent pas les interets
+var ErrPayoffNotReached = errors.New("les mensualites ne couvrent pas les interets")
+
+// isLiability indique un compte de dette
+func isLiability(acc *db.Account) bool {
+	return acc.Kind == "LIABILITY"
+}
+
+// repayDebt fait evoluer une dette d'un mois : interets puis mensualite minimale.
+// Retourne le nouveau solde (negatif) et la mensualite versee.
+func repayDebt(acc *db.Account, balance float64) (float64, float64) {
+	owed := -balance
+	if owed <= 0 {
+		return balance, 0
+	}
+	owed += owed * acc.DebtRate / 100 / 12
+	payment := math.Min(acc.DebtMinPayment, owed)
+	return -(owed - payment), payment
+}
+
+// Debt est une dette a rembourser
+type Debt struct {
+	AccountID  int64   `json:"accountId"`
+	Name       string  `json:"name"`
+	Balance    float64 `json:"balance"` // Capital restant du (positif)
+	Rate       float64 `json:"rate"`    // % annuel
+	MinPayment float64 `json:"minPayment"`
+}
+
+// DebtsFromAccounts retourne les dettes en cours des comptes LIABILITY
+func DebtsFromAccounts(accounts []db.Account) []Debt {
+	var debts []Debt
+	for i := range accounts {
+		acc := &accounts[i]
+		if isLiability(acc) && acc.Balance < 0 {
+			debts = append(debts, Debt{
+				AccountID:  acc.ID,
+				Name:       acc.Name,
+				Balance:    -acc.Balance,
+				Rate:       acc.DebtRate,
+				MinPayment: acc.DebtMinPayment,
+			})
+		}
+	}
+	return debts
+}
+
+// PayoffPayment est le remboursement d'une dette sur un mois
+type PayoffPayment struct {
+	AccountID int64   `json:"accountId"`
+	Interest  float64 `json:"interest"`
+	Payment   float64 `json:"payment"`
+	Balance   float64 `json:"balance"` // Restant du apres paiement
+}
+
+// PayoffMonth est une ligne de l'echeancier
+type PayoffMonth struct {
+	Month        int             `json:"month"`
+	Date         string          `json:"date"` // YYYY-MM
+	Payments     []PayoffPayment `json:"payments"`
+	TotalBalance float64         `json:"totalBalance"`
+}
+
+// DebtPayoff resume le remboursement d'une dette dans un plan
+type DebtPayoff struct {
+	AccountID  int64   `json:"accountId"`
+	Name       string  `json:"name"`
+	Months     int     `json:"months"`
+	PayoffDate string  `json:"payoffDate"`
+	Interest   float64 `json:"interest"`
+}
+
+// PayoffPlan est le resultat d'une strategie de remboursement
+type PayoffPlan struct {
+	Strategy      string        `json:"strategy"`
+	Order         []int64       `json:"order"` // Dettes ciblees par le surplus, dans l'ordre
+	Months        int           `json:"months"`
+	PayoffDate    string        `json:"payoffDate"`
+	TotalInterest float64       `json:"totalInterest"`
+	TotalPaid     float64       `json:"totalPaid"`
+	Debts         []DebtPayoff  `json:"debts"`
+	Schedule      []PayoffMonth `json:"schedule"`
+}
+
+// PayoffOrder retourne l'ordre dans lequel le surplus rembourse les dettes.
+// En ordre personnalise, les dettes non citees suivent selon l'avalanche.
+func PayoffOrder(debts []Debt, strategy string, custom []int64) []int64 {
+	sorted := make([]Debt, len(debts))
+	copy(sorted, debts)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if strategy == StrategySnowball && a.Balance != b.Balance {
+			return a.Balance < b.Balance
+		}
+		if a.Rate != b.Rate {
+			return a.Rate > b.Rate
+		}
+		return a.Balance < b.Balance
+	})
+
+	order := make([]int64, 0, len(debts))
+	seen := make(map[int64]bool)
+	if strategy == StrategyCustom {
+		known := make(map[int64]bool)
+		for _, d := range debts {
+			known[d.AccountID] = true
+		}
+		for _, id := range custom {
+			if known[id] && !seen[id] {
+				order = append(order, id)
+				seen[id] = true
+			}
+		}
+	}
+	for _, d := range sorted {
+		if !seen[d.AccountID] {
+			order = append(order, d.AccountID)
+		}
+	}
+	return order
+}
+
+// PlanPayoff simule le remboursement mois par mois : interets, mensualites minimales,
+// puis le surplus (extra et mensualites des dettes deja soldees) sur la dette
+// prioritaire. Le budget mensuel reste constant jusqu'au remboursement complet.
+func PlanPayoff(debts []Debt, extra float64, strategy string, custom []int64, start time.Time) (PayoffPlan, error) {
+	order := PayoffOrder(debts, strategy, custom)
+	plan := PayoffPlan{Strategy: strategy, Order: order, Debts: []DebtPayoff{}, Schedule: []PayoffMonth{}}
+	if len(debts) == 0 {
+		return plan, nil
+	}
+
+	budget := math.Max(extra, 0)
+	balances := make(map[int64]float64)
+	index := make(map[int64]int)
+	var total float64
+	for i, d := range debts {
+		budget += d.MinPayment
+		balances[d.AccountID] = d.Balance
+		index[d.AccountID] = i
+		total += d.Balance
+		plan.Debts = append(plan.Debts, DebtPayoff{AccountID: d.AccountID, Name: d.Name})
+	}
+
+	first := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, start.Location())
+	for m := 1; total > epsilonDebt; m++ {
+		if m > maxPayoffMonths {
+			return plan, ErrPayoffNotReached
+		}
+		date := first.AddDate(0, m, 0).Format("2006-01")
+		payments := make(map[int64]*PayoffPayment)
+		available := budget
+
+		// Interets du mois puis mensualites minimales
+		for _, d := range debts {
+			balance := balances[d.AccountID]
+			if balance <= 0 {
+				continue
+			}
+			interest := roundCents(balance * d.Rate / 100 / 12)
+			balance += interest
+			payment := math.Min(d.MinPayment, balance)
+			balances[d.AccountID] = balance - payment
+			available -= payment
+			payments[d.AccountID] = &PayoffPayment{AccountID: d.AccountID, Interest: interest, Payment: payment}
+			plan.Debts[index[d.AccountID]].Interest += interest
+			plan.TotalInterest += interest
+		}
+
+		// Surplus sur les dettes prioritaires
+		for _, id := range order {
+			if available <= 0 {
+				break
+			}
+			p, ok := payments[id]
+			if !ok || balances[id] <= 0 {
+				continue
+			}
+			extraPayment := math.Min(available, balances[id])
+			balances[id] -= extraPayment
+			p.Payment += extraPayment
+			available -= extraPayment
+		}
+
+		month := PayoffMonth{Month: m, Date: date, Payments: make([]PayoffPayment, 0, len(payments))}
+		previous := total
+		total = 0
+		for _, d := range debts {
+			p, ok := payments[d.AccountID]
+			if !ok {
+				continue
+			}
+			p.Balance = roundCents(balances[d.AccountID])
+			p.Payment = roundCents(p.Payment)
+			plan.TotalPaid += p.Payment
+			month.Payments = append(month.Payments, *p)
+			total += balances[d.AccountID]
+			if balances[d.AccountID] <= epsilonDebt {
+				balances[d.AccountID] = 0
+				debt := &plan.Debts[index[d.AccountID]]
+				debt.Months, debt.PayoffDate = m, date
+			}
+		}
+		month.TotalBalance = roundCents(total)
+		plan.Schedule = append(plan.Schedule, month)
+
+		if total >= previous-epsilonDebt {
+			return plan, ErrPayoffNotReached
+		}
+		plan.Months, plan.PayoffDate = m, date
+	}
+
+	for i := range plan.Debts {
+		plan.Debts[i].Interest = roundCents(plan.Debts[i].Interest)
+	}
+	plan.TotalInterest = roundCents(plan.TotalInterest)
+	plan.TotalPaid = roundCents(plan.TotalPaid)
+	return plan, nil
+}
+
+// epsilonDebt est la tolerance d'une dette consideree comme soldee
+const epsilonDebt = 0.005
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
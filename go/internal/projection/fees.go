@@ -0,0 +1,30 @@
+package projection
+
+import (
+	"math"
+
+	"pilot-finance/internal/db"
+)
+
+// hasFees indique si le compte supporte des frais de gestion
+func hasFees(acc *db.Account) bool {
+	return acc.FeeAnnualRate > 0 || acc.FeeDepositRate > 0 || acc.FeeFixedYearly > 0
+}
+
+// monthlyFee calcule les frais de gestion d'un mois (pourcentage de l'encours
+// et quote-part des frais fixes annuels), sans depasser le solde disponible
+func monthlyFee(acc *db.Account, balance float64) float64 {
+	if balance <= 0 {
+		return 0
+	}
+	fee := balance*acc.FeeAnnualRate/100/12 + acc.FeeFixedYearly/12
+	return math.Min(fee, balance)
+}
+
+// depositFee calcule les frais d'entree preleves sur un versement
+func depositFee(acc *db.Account, amount float64) float64 {
+	if amount <= 0 || acc.FeeDepositRate <= 0 {
+		return 0
+	}
+	return amount * acc.FeeDepositRate / 100
+}
@@ -0,0 +1,211 @@
+package projection
+
+import (
+	"math"
+	"time"
+
+	"pilot-finance/internal/db"
+	"pilot-finance/internal/ledger"
+)
+
+// ForecastOperation est une operation prevue un jour donne
+type ForecastOperation struct {
+	Description string  `json:"description"`
+	AccountID   int64   `json:"accountId"`
+	AccountName string  `json:"accountName"`
+	Amount      float64 `json:"amount"`
+}
+
+// ForecastBalance est le solde prevu d'un compte en fin de journee
+type ForecastBalance struct {
+	AccountID int64   `json:"accountId"`
+	Name      string  `json:"name"`
+	Balance   float64 `json:"balance"`
+}
+
+// ForecastDay est le solde prevu de chaque compte en fin de journee, dans l'ordre des comptes
+type ForecastDay struct {
+	Date       string              `json:"date"`
+	Total      float64             `json:"total"`
+	Accounts   []ForecastBalance   `json:"accounts"`
+	Operations []ForecastOperation `json:"operations,omitempty"`
+	Overdrawn  []int64             `json:"overdrawn,omitempty"` // Comptes courants a decouvert
+}
+
+// Overdraft est une periode de decouvert prevue sur un compte courant
+type Overdraft struct {
+	AccountID   int64   `json:"accountId"`
+	AccountName string  `json:"accountName"`
+	From        string  `json:"from"`
+	To          string  `json:"to"`
+	Lowest      float64 `json:"lowest"`
+	LowestDate  string  `json:"lowestDate"`
+}
+
+// ForecastResult est la prevision de tresorerie jour par jour
+type ForecastResult struct {
+	Days       []ForecastDay `json:"days"`
+	Overdrafts []Overdraft   `json:"overdrafts"`
+}
+
+// isCashAccount indique un compte courant (ni epargne remuneree, ni bien, ni terme),
+// surveille pour les decouverts
+func isCashAccount(acc *db.Account) bool {
+	return (acc.Kind == "" || acc.Kind == "STANDARD") && !acc.IsYieldActive
+}
+
+// dayInMonth retourne le jour d'execution d'une operation mensuelle dans le mois de
+// date ; un jour absent du mois (31 en avril) est reporte au dernier jour du mois
+func dayInMonth(dayOfMonth int, date time.Time) int {
+	last := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, date.Location()).Day()
+	if dayOfMonth > last {
+		return last
+	}
+	return dayOfMonth
+}
+
+// occursOn indique si une operation mensuelle tombe ce jour-la
+func occursOn(dayOfMonth int, date time.Time) bool {
+	return date.Day() == dayInMonth(dayOfMonth, date)
+}
+
+// ranInMonth indique si une operation a deja ete executee le mois de date
+func ranInMonth(rec db.RecurringOperation, date time.Time) bool {
+	return rec.LastRunDate != nil &&
+		rec.LastRunDate.Year() == date.Year() && rec.LastRunDate.Month() == date.Month()
+}
+
+// beforeCreation indique une echeance anterieure au jour de creation de l'operation
+func beforeCreation(rec db.RecurringOperation, date time.Time) bool {
+	if rec.CreatedAt == nil {
+		return false
+	}
+	c := rec.CreatedAt.In(date.Location())
+	return date.Before(time.Date(c.Year(), c.Month(), c.Day(), 0, 0, 0, 0, date.Location()))
+}
+
+// IsDue indique si une operation recurrente active a atteint son jour ce mois-ci sans
+// y avoir encore ete executee ; une echeance anterieure a sa creation est ignoree
+func IsDue(rec db.RecurringOperation, now time.Time) bool {
+	return rec.IsActive && now.Day() >= dayInMonth(rec.DayOfMonth, now) && !ranInMonth(rec, now) &&
+		!beforeCreation(rec, RunDate(rec, now))
+}
+
+// RunDate retourne la date d'execution d'une operation le mois de now
+func RunDate(rec db.RecurringOperation, now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), dayInMonth(rec.DayOfMonth, now), 0, 0, 0, 0, now.Location())
+}
+
+// Forecast projette les soldes jour par jour a partir des soldes actuels, des operations
+// recurrentes a leur jour du mois avec la repartition des revenus, et des evenements
+// ponctuels (descriptions dechiffrees). Le jour 0 est aujourd'hui ; une operation deja
+// executee ce mois-ci n'y est pas rejouee.
+func Forecast(accounts []db.Account, opts Options, today time.Time, days int) ForecastResult {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+
+	balances := make(map[int64]float64)
+	accountByID := make(map[int64]*db.Account)
+	for i := range accounts {
+		balances[accounts[i].ID] = accounts[i].Balance
+		accountByID[accounts[i].ID] = &accounts[i]
+	}
+
+	rulesByRecurring := db.AllocationRulesByRecurring(opts.Allocations)
+	eventsByDay := make(map[string][]db.PlannedEvent)
+	for _, e := range opts.Events {
+		key := e.Date.Format("2006-01-02")
+		eventsByDay[key] = append(eventsByDay[key], e)
+	}
+
+	result := ForecastResult{Days: make([]ForecastDay, 0, days+1), Overdrafts: []Overdraft{}}
+	open := make(map[int64]*Overdraft) // decouverts en cours
+
+	for d := 0; d <= days; d++ {
+		date := today.AddDate(0, 0, d)
+		key := date.Format("2006-01-02")
+		day := ForecastDay{Date: key, Accounts: make([]ForecastBalance, 0, len(accounts))}
+
+		apply := func(accountID int64, amount float64, description string) {
+			acc, ok := accountByID[accountID]
+			if !ok {
+				return
+			}
+			balances[accountID] += amount
+			day.Operations = append(day.Operations, ForecastOperation{
+				Description: description,
+				AccountID:   acc.ID,
+				AccountName: acc.Name,
+				Amount:      amount,
+			})
+		}
+
+		for _, rec := range opts.Recurrings {
+			if !rec.IsActive || !occursOn(rec.DayOfMonth, date) || beforeCreation(rec, date) {
+				continue
+			}
+			if d == 0 && ranInMonth(rec, date) {
+				continue
+			}
+			if rec.ToAccountID != nil {
+				amount := math.Abs(rec.Amount)
+				apply(rec.AccountID, -amount, rec.Description)
+				apply(*rec.ToAccountID, amount, rec.Description)
+				continue
+			}
+			apply(rec.AccountID, rec.Amount, rec.Description)
+			for _, a := range ledger.AllocateIncome(rec.Amount, rulesByRecurring[rec.ID]) {
+				apply(rec.AccountID, -a.Amount, "Repartition "+rec.Description)
+				apply(a.ToAccountID, a.Amount, "Repartition "+rec.Description)
+			}
+		}
+
+		for _, e := range eventsByDay[key] {
+			apply(e.AccountID, e.Amount, e.Description)
+		}
+
+		for _, acc := range accounts {
+			balance := balances[acc.ID]
+			day.Accounts = append(day.Accounts, ForecastBalance{
+				AccountID: acc.ID,
+				Name:      acc.Name,
+				Balance:   math.Round(balance*100) / 100,
+			})
+			day.Total += balance
+
+			if !isCashAccount(&acc) {
+				continue
+			}
+			o := open[acc.ID]
+			if balance >= 0 {
+				if o != nil {
+					result.Overdrafts = append(result.Overdrafts, *o)
+					delete(open, acc.ID)
+				}
+				continue
+			}
+			day.Overdrawn = append(day.Overdrawn, acc.ID)
+			if o == nil {
+				o = &Overdraft{AccountID: acc.ID, AccountName: acc.Name, From: key, Lowest: balance, LowestDate: key}
+				open[acc.ID] = o
+			}
+			o.To = key
+			if balance < o.Lowest {
+				o.Lowest = balance
+				o.LowestDate = key
+			}
+		}
+		day.Total = math.Round(day.Total*100) / 100
+		result.Days = append(result.Days, day)
+	}
+
+	// Decouverts encore en cours a la fin de l'horizon, dans l'ordre des comptes
+	for _, acc := range accounts {
+		if o := open[acc.ID]; o != nil {
+			result.Overdrafts = append(result.Overdrafts, *o)
+		}
+	}
+	for i := range result.Overdrafts {
+		result.Overdrafts[i].Lowest = math.Round(result.Overdrafts[i].Lowest*100) / 100
+	}
+	return result
+}
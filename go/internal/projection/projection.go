@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"pilot-finance/internal/db"
+	"pilot-finance/internal/ledger"
 )
 
 // YearData represente les donnees d'une annee de projection
@@ -16,18 +17,44 @@ type YearData struct {
 	TotalMax float64                `json:"totalMax"`
 	TotalAvg float64                `json:"totalAvg"`
 	Accounts map[string]float64     `json:"accounts"`
+	Events   []EventMarker          `json:"events,omitempty"`
+}
+
+// EventMarker annote un point de projection avec un evenement ponctuel
+type EventMarker struct {
+	Description string  `json:"description"`
+	AccountName string  `json:"accountName"`
+	Amount      float64 `json:"amount"`
+	Date        string  `json:"date"`
+}
+
+// Options contient les donnees optionnelles de la simulation
+type Options struct {
+	// Events sont les evenements ponctuels prevus (descriptions dechiffrees)
+	Events []db.PlannedEvent
+	// Recurrings et Allocations servent a repartir chaque mois les revenus
+	// recurrents vers les comptes cibles des regles de repartition
+	Recurrings  []db.RecurringOperation
+	Allocations []db.AllocationRule
 }
 
 // DashboardData contient toutes les donnees du dashboard
 type DashboardData struct {
-	Accounts       []db.Account      `json:"accounts"`
-	Projection     []YearData        `json:"projection"`
-	TotalInterests float64           `json:"totalInterests"`
-	TotalBalance   float64           `json:"totalBalance"`
+	Accounts       []db.Account       `json:"accounts"`
+	Projection     []YearData         `json:"projection"`
+	TotalInterests float64            `json:"totalInterests"`
+	TotalBalance   float64            `json:"totalBalance"`
+	TotalFees      float64            `json:"totalFees"`
+	FeesByAccount  map[string]float64 `json:"feesByAccount"`
 }
 
 // Calculate calcule les projections sur N annees avec simulation mois par mois
 func Calculate(accounts []db.Account, years int) DashboardData {
+	return CalculateWithOptions(accounts, years, Options{})
+}
+
+// CalculateWithOptions calcule les projections en tenant compte des options
+func CalculateWithOptions(accounts []db.Account, years int, opts Options) DashboardData {
 	var totalBalance float64
 
 	// Calculer le solde total actuel
@@ -81,24 +108,144 @@ func Calculate(accounts []db.Account, years int) DashboardData {
 		projection = append(projection, createYearData(0, formatYearName(0)))
 	}
 
+	// Frais de gestion cumules sur l'horizon
+	var totalFees float64
+	feesByID := make(map[int64]float64)
+
+	// deposit credite un versement sur un compte, net des frais d'entree
+	deposit := func(id int64, amount float64) {
+		if acc, ok := accountByID[id]; ok {
+			fee := depositFee(acc, amount)
+			amount -= fee
+			totalFees += fee
+			feesByID[id] += fee
+		}
+		balances[id] += amount
+	}
+
 	// Simuler mois par mois
+	start := time.Now()
+	var valuationDelta float64 // variation de valeur des biens, exclue des interets
+	var flowsDelta float64     // evenements ponctuels, repartitions et mensualites, exclus des interets
+
+	// Ranger les evenements futurs par mois simule
+	eventsByMonth := make(map[int][]db.PlannedEvent)
+	for _, e := range opts.Events {
+		if !e.Date.After(start) {
+			continue
+		}
+		m := (e.Date.Year()-start.Year())*12 + int(e.Date.Month()) - int(start.Month())
+		if m < 1 {
+			m = 1 // Evenement plus tard dans le mois courant
+		}
+		if m <= totalMonths {
+			eventsByMonth[m] = append(eventsByMonth[m], e)
+		}
+	}
+	var pendingMarkers []EventMarker // annotations du prochain point enregistre
+
+	// Parts des revenus recurrents versees chaque mois selon les regles de repartition.
+	// Le revenu lui-meme et les depenses courantes ne sont pas simules : seules les
+	// parts epargnees alimentent les comptes cibles.
+	rulesByRecurring := db.AllocationRulesByRecurring(opts.Allocations)
+	var allocations []ledger.Allocation
+	for _, rec := range opts.Recurrings {
+		if rec.IsActive && rec.ToAccountID == nil {
+			allocations = append(allocations, ledger.AllocateIncome(rec.Amount, rulesByRecurring[rec.ID])...)
+		}
+	}
+
+	// Termes en cours des comptes a terme
+	terms := make(map[int64]*termState)
+	for id, acc := range accountByID {
+		if isTermDeposit(acc) {
+			st := currentTerm(acc, start)
+			terms[id] = &st
+		}
+	}
+
 	for m := 1; m <= totalMonths; m++ {
+		date := start.AddDate(0, m, 0)
+
+		// Appliquer les evenements ponctuels du mois
+		for _, e := range eventsByMonth[m] {
+			if _, ok := balances[e.AccountID]; !ok {
+				continue
+			}
+			if e.Amount > 0 {
+				deposit(e.AccountID, e.Amount)
+			} else {
+				balances[e.AccountID] += e.Amount
+			}
+			flowsDelta += e.Amount
+			pendingMarkers = append(pendingMarkers, EventMarker{
+				Description: e.Description,
+				AccountName: nameByID[e.AccountID],
+				Amount:      e.Amount,
+				Date:        e.Date.Format("2006-01-02"),
+			})
+		}
+
+		// Repartition des revenus recurrents
+		for _, a := range allocations {
+			if _, ok := balances[a.ToAccountID]; !ok {
+				continue
+			}
+			deposit(a.ToAccountID, a.Amount)
+			flowsDelta += a.Amount
+		}
+
+		// Echeances des comptes a terme : versement des interets puis
+		// renouvellement ou virement du capital vers le compte choisi
+		for id, st := range terms {
+			acc := accountByID[id]
+			for st.active && !st.maturity.After(date) {
+				balances[id] += termInterest(balances[id], acc.TermRate, st.start, st.maturity)
+				if acc.TermRollover == "ROLLOVER" {
+					st.start, st.maturity = nextTerm(st.start, st.maturity)
+					continue
+				}
+				st.active = false
+				if acc.TermTargetAccountID != nil {
+					if _, ok := balances[*acc.TermTargetAccountID]; ok {
+						deposit(*acc.TermTargetAccountID, balances[id])
+						balances[id] = 0
+					}
+				}
+			}
+		}
+
+		// Dettes : interets puis mensualite minimale, payee hors des comptes simules
+		for id, acc := range accountByID {
+			if isLiability(acc) {
+				newBalance, payment := repayDebt(acc, balances[id])
+				balances[id] = newBalance
+				flowsDelta += payment
+			}
+		}
+
+		// Faire evoluer la valeur des biens (appreciation ou amortissement)
+		for id, acc := range accountByID {
+			if isValuedAsset(acc) {
+				newValue := valueAsset(acc, balances[id], date)
+				valuationDelta += newValue - balances[id]
+				balances[id] = newValue
+			}
+		}
+
 		// Calculer les interets de chaque compte avec rendement
 		// et les redistribuer selon le taux de reinvestissement
 		payouts := make(map[int64]float64) // payouts a ajouter aux comptes cibles
 
 		for id, acc := range accountByID {
-			if !acc.IsYieldActive {
+			if !earnsYield(acc) {
 				continue
 			}
 
 			currentBalance := balances[id]
 
-			// Taux moyen mensuel
-			rate := acc.YieldMin
-			if acc.YieldType == "RANGE" {
-				rate = (acc.YieldMin + acc.YieldMax) / 2
-			}
+			// Taux mensuel (bareme en vigueur ou taux moyen)
+			rate := accountRate(acc, currentBalance, date)
 			monthlyRate := rate / 100 / 12
 
 			// Interet du mois
@@ -118,30 +265,54 @@ func Calculate(accounts []db.Account, years int) DashboardData {
 
 		// Ajouter les payouts aux comptes cibles
 		for targetID, amount := range payouts {
-			balances[targetID] += amount
+			deposit(targetID, amount)
+		}
+
+		// Prelever les frais de gestion sur l'encours
+		for id, acc := range accountByID {
+			if !hasFees(acc) {
+				continue
+			}
+			fee := monthlyFee(acc, balances[id])
+			balances[id] -= fee
+			totalFees += fee
+			feesByID[id] += fee
 		}
 
 		// Enregistrer le point de donnees selon le mode d'affichage
 		if useMonths {
-			projection = append(projection, createYearData(m, formatMonthName(m)))
+			yearData := createYearData(m, formatMonthName(m))
+			yearData.Events, pendingMarkers = pendingMarkers, nil
+			projection = append(projection, yearData)
 		} else if m%12 == 0 {
 			yearIndex := m / 12
-			projection = append(projection, createYearData(yearIndex, formatYearName(yearIndex)))
+			yearData := createYearData(yearIndex, formatYearName(yearIndex))
+			yearData.Events, pendingMarkers = pendingMarkers, nil
+			projection = append(projection, yearData)
 		}
 	}
 
-	// Calculer les interets totaux (difference entre solde final et initial)
+	// Calculer les interets totaux bruts (difference entre solde final et initial,
+	// hors evolution de la valeur des biens, evenements ponctuels, repartitions et
+	// mensualites de dettes, avant frais) ; les interets des dettes viennent en deduction
 	var finalTotal float64
 	for _, balance := range balances {
 		finalTotal += balance
 	}
-	totalInterests := finalTotal - totalBalance
+	totalInterests := finalTotal - totalBalance - valuationDelta - flowsDelta + totalFees
+
+	feesByAccount := make(map[string]float64)
+	for id, fees := range feesByID {
+		feesByAccount[nameByID[id]] = math.Round(fees)
+	}
 
 	return DashboardData{
 		Accounts:       accounts,
 		Projection:     projection,
 		TotalInterests: math.Round(totalInterests),
 		TotalBalance:   totalBalance,
+		TotalFees:      math.Round(totalFees),
+		FeesByAccount:  feesByAccount,
 	}
 }
 
@@ -158,14 +329,12 @@ type YieldPayout struct {
 // CalculateMonthlyYieldPayout calcule les revenus mensuels de rendement
 func CalculateMonthlyYieldPayout(accounts []db.Account) float64 {
 	var monthlyPayout float64
+	now := time.Now()
 
 	for _, acc := range accounts {
-		if acc.IsYieldActive {
-			// Taux moyen
-			rate := acc.YieldMin
-			if acc.YieldType == "RANGE" {
-				rate = (acc.YieldMin + acc.YieldMax) / 2
-			}
+		if earnsYield(&acc) {
+			// Taux en vigueur
+			rate := accountRate(&acc, acc.Balance, now)
 
 			// Gain annuel
 			annualGain := acc.Balance * (rate / 100)
@@ -183,14 +352,12 @@ func CalculateMonthlyYieldPayout(accounts []db.Account) float64 {
 // CalculateYieldPayouts calcule les payouts detailles par compte
 func CalculateYieldPayouts(accounts []db.Account, accountNames map[int64]string) []YieldPayout {
 	var payouts []YieldPayout
+	now := time.Now()
 
 	for _, acc := range accounts {
-		if acc.IsYieldActive && acc.ReinvestmentRate < 100 && acc.TargetAccountID != nil {
-			// Taux moyen
-			rate := acc.YieldMin
-			if acc.YieldType == "RANGE" {
-				rate = (acc.YieldMin + acc.YieldMax) / 2
-			}
+		if earnsYield(&acc) && acc.ReinvestmentRate < 100 && acc.TargetAccountID != nil {
+			// Taux en vigueur
+			rate := accountRate(&acc, acc.Balance, now)
 
 			// Gain annuel
 			annualGain := acc.Balance * (rate / 100)
@@ -227,15 +394,17 @@ type MonthlySummary struct {
 	Net       float64 `json:"net"`
 	Yield     float64 `json:"yield"`
 	Transfers float64 `json:"transfers"`
+	// Sorties recurrentes par categorie budgetaire (vide si non categorisee)
+	ExpensesByCategory map[string]float64 `json:"expensesByCategory"`
 }
 
 func CalculateMonthlySummary(recurrings []db.RecurringOperation, accounts []db.Account) MonthlySummary {
-	var summary MonthlySummary
+	summary := MonthlySummary{ExpensesByCategory: make(map[string]float64)}
 
 	// Creer une map des comptes avec rendement
 	yieldAccounts := make(map[int64]bool)
 	for _, acc := range accounts {
-		if acc.IsYieldActive {
+		if earnsYield(&acc) {
 			yieldAccounts[acc.ID] = true
 		}
 	}
@@ -250,6 +419,11 @@ func CalculateMonthlySummary(recurrings []db.RecurringOperation, accounts []db.A
 			summary.Income += rec.Amount
 		} else {
 			summary.Expenses += math.Abs(rec.Amount)
+			category := ""
+			if rec.Category != nil {
+				category = *rec.Category
+			}
+			summary.ExpensesByCategory[category] += math.Abs(rec.Amount)
 		}
 	}
 
@@ -0,0 +1,401 @@
+package projection
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"pilot-finance/internal/db"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestAccountRateFallback(t *testing.T) {
+	acc := db.Account{YieldType: "RANGE", YieldMin: 2, YieldMax: 4}
+	if got := accountRate(&acc, 1000, date(2025, 1, 1)); got != 3 {
+		t.Errorf("accountRate = %v, want 3", got)
+	}
+}
+
+func TestAccountRateSchedule(t *testing.T) {
+	// Livret A : 3% puis 2.4% au 1er fevrier
+	acc := db.Account{YieldMin: 1, RateSchedule: []db.RateSegment{
+		{EffectiveDate: date(2024, 8, 1), Rate: 3},
+		{EffectiveDate: date(2025, 2, 1), Rate: 2.4},
+	}}
+
+	tests := []struct {
+		at   time.Time
+		want float64
+	}{
+		{date(2024, 6, 1), 1}, // Avant le bareme : taux du compte
+		{date(2024, 9, 1), 3},
+		{date(2025, 2, 1), 2.4},
+	}
+	for _, tt := range tests {
+		if got := accountRate(&acc, 1000, tt.at); got != tt.want {
+			t.Errorf("accountRate(%s) = %v, want %v", tt.at.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
+
+func TestAccountRateTiers(t *testing.T) {
+	// Taux promotionnel de 5% jusqu'a 10k, 2% au-dela
+	acc := db.Account{RateSchedule: []db.RateSegment{
+		{EffectiveDate: date(2024, 1, 1), TierMin: 10000, Rate: 2},
+		{EffectiveDate: date(2024, 1, 1), TierMin: 0, Rate: 5},
+	}}
+
+	if got := accountRate(&acc, 5000, date(2025, 1, 1)); got != 5 {
+		t.Errorf("accountRate(5000) = %v, want 5", got)
+	}
+	// 10000*5% + 10000*2% = 700 sur 20000 => 3.5%
+	if got := accountRate(&acc, 20000, date(2025, 1, 1)); math.Abs(got-3.5) > 1e-9 {
+		t.Errorf("accountRate(20000) = %v, want 3.5", got)
+	}
+}
+
+func TestCalculateFees(t *testing.T) {
+	target := int64(1)
+	accounts := []db.Account{
+		{ID: 1, Name: "AV", Balance: 12000, FeeFixedYearly: 120, FeeDepositRate: 10},
+		{ID: 2, Name: "Livret", Balance: 1000, IsYieldActive: true, YieldMin: 12, TargetAccountID: &target},
+	}
+
+	data := Calculate(accounts, 1)
+
+	// 12 * 10 de frais fixes + 10% des 12 versements d'interets de 10
+	if data.TotalFees != 132 {
+		t.Errorf("TotalFees = %v, want 132", data.TotalFees)
+	}
+	if data.FeesByAccount["AV"] != 132 {
+		t.Errorf("FeesByAccount = %v", data.FeesByAccount)
+	}
+	// Interets bruts, avant frais
+	if data.TotalInterests != 120 {
+		t.Errorf("TotalInterests = %v, want 120", data.TotalInterests)
+	}
+	final := data.Projection[len(data.Projection)-1]
+	if final.Accounts["AV"] != 11988 || final.Accounts["Livret"] != 1000 {
+		t.Errorf("final = %v", final.Accounts)
+	}
+}
+
+func TestCalculateEvents(t *testing.T) {
+	accounts := []db.Account{
+		{ID: 1, Name: "AV", Balance: 12000, FeeFixedYearly: 120, FeeDepositRate: 10},
+	}
+	events := []db.PlannedEvent{
+		{AccountID: 1, Amount: 1000, Description: "Heritage", Date: time.Now().AddDate(0, 3, 0)},
+	}
+
+	data := CalculateWithOptions(accounts, 1, Options{Events: events})
+
+	// 12 * 10 de frais fixes + 10% de 1000 a l'entree
+	if data.TotalFees != 220 {
+		t.Errorf("TotalFees = %v, want 220", data.TotalFees)
+	}
+	if data.TotalInterests != 0 {
+		t.Errorf("TotalInterests = %v, want 0", data.TotalInterests)
+	}
+	final := data.Projection[len(data.Projection)-1]
+	if final.TotalAvg != 12780 {
+		t.Errorf("final total = %v, want 12780", final.TotalAvg)
+	}
+
+	var markers int
+	for _, p := range data.Projection {
+		markers += len(p.Events)
+	}
+	if markers != 1 {
+		t.Errorf("event markers = %d, want 1", markers)
+	}
+}
+
+func forecastBalance(day ForecastDay, accountID int64) float64 {
+	for _, b := range day.Accounts {
+		if b.AccountID == accountID {
+			return b.Balance
+		}
+	}
+	return math.NaN()
+}
+
+func TestForecastOverdraft(t *testing.T) {
+	savings := int64(2)
+	ran := date(2025, 4, 2)
+	accounts := []db.Account{
+		{ID: 1, Name: "Courant", Balance: 500},
+		{ID: 2, Name: "Livret", Balance: 1000, Kind: "STANDARD", IsYieldActive: true},
+	}
+	recurrings := []db.RecurringOperation{
+		{AccountID: 1, Amount: -900, DayOfMonth: 5, IsActive: true, Description: "Loyer"},
+		{AccountID: 1, Amount: 2000, DayOfMonth: 31, IsActive: true, Description: "Salaire"},
+		{AccountID: 1, ToAccountID: &savings, Amount: -100, DayOfMonth: 2, IsActive: true, LastRunDate: &ran},
+	}
+
+	f := Forecast(accounts, Options{Recurrings: recurrings}, date(2025, 4, 2), 30)
+	if len(f.Days) != 31 {
+		t.Fatalf("days = %d", len(f.Days))
+	}
+	// Virement du jour deja execute, loyer le 5, salaire le 30 (dernier jour d'avril)
+	if got := forecastBalance(f.Days[0], 1); got != 500 {
+		t.Errorf("jour 0 = %v", got)
+	}
+	if got := forecastBalance(f.Days[3], 1); got != -400 || len(f.Days[3].Overdrawn) != 1 || f.Days[3].Overdrawn[0] != 1 {
+		t.Errorf("5 avril = %+v", f.Days[3])
+	}
+	if len(f.Overdrafts) != 1 {
+		t.Fatalf("overdrafts = %+v", f.Overdrafts)
+	}
+	o := f.Overdrafts[0]
+	if o.From != "2025-04-05" || o.To != "2025-04-29" || o.Lowest != -400 {
+		t.Errorf("overdraft = %+v", o)
+	}
+	// Le virement du 2 mai alimente le livret
+	if got := forecastBalance(f.Days[30], 2); got != 1100 {
+		t.Errorf("livret = %v", got)
+	}
+}
+
+func TestForecastAllocations(t *testing.T) {
+	created := date(2025, 4, 10)
+	accounts := []db.Account{
+		{ID: 1, Name: "Courant", Balance: 100},
+		{ID: 2, Name: "PEA", Balance: 0},
+	}
+	recurrings := []db.RecurringOperation{
+		{ID: 7, AccountID: 1, Amount: 2000, DayOfMonth: 15, IsActive: true, Description: "Salaire"},
+		// Creee le 10 avril, apres son jour : demarre en mai
+		{ID: 8, AccountID: 1, Amount: -50, DayOfMonth: 5, IsActive: true, CreatedAt: &created},
+		// Creee le 10 avril avant son jour : executee des avril
+		{ID: 9, AccountID: 1, Amount: -20, DayOfMonth: 12, IsActive: true, CreatedAt: &created},
+	}
+	rules := []db.AllocationRule{{RecurringID: 7, ToAccountID: 2, Percent: 25}}
+
+	f := Forecast(accounts, Options{Recurrings: recurrings, Allocations: rules}, created, 30)
+	// 15 avril : salaire puis 500 vers le PEA
+	if got := forecastBalance(f.Days[2], 1); got != 80 {
+		t.Errorf("courant 12 avril = %v", got)
+	}
+	if got := forecastBalance(f.Days[5], 1); got != 1580 {
+		t.Errorf("courant 15 avril = %v", got)
+	}
+	if got := forecastBalance(f.Days[5], 2); got != 500 || len(f.Days[5].Operations) != 3 {
+		t.Errorf("15 avril = %+v", f.Days[5])
+	}
+	// 5 mai : premiere execution de l'operation creee en avril
+	if got := forecastBalance(f.Days[25], 1); got != 1530 {
+		t.Errorf("courant 5 mai = %v", got)
+	}
+}
+
+func TestIsDue(t *testing.T) {
+	created := date(2025, 4, 10)
+	tests := []struct {
+		name string
+		rec  db.RecurringOperation
+		now  time.Time
+		want bool
+	}{
+		{"jour atteint", db.RecurringOperation{DayOfMonth: 5, IsActive: true}, date(2025, 4, 10), true},
+		{"jour a venir", db.RecurringOperation{DayOfMonth: 15, IsActive: true}, date(2025, 4, 10), false},
+		{"inactive", db.RecurringOperation{DayOfMonth: 5}, date(2025, 4, 10), false},
+		{"creee apres son jour", db.RecurringOperation{DayOfMonth: 5, IsActive: true, CreatedAt: &created}, date(2025, 4, 20), false},
+		{"creee avant son jour", db.RecurringOperation{DayOfMonth: 12, IsActive: true, CreatedAt: &created}, date(2025, 4, 12), true},
+		{"creee le jour meme", db.RecurringOperation{DayOfMonth: 10, IsActive: true, CreatedAt: &created}, date(2025, 4, 10), true},
+		{"mois suivant", db.RecurringOperation{DayOfMonth: 5, IsActive: true, CreatedAt: &created}, date(2025, 5, 5), true},
+		{"deja executee", db.RecurringOperation{DayOfMonth: 5, IsActive: true, LastRunDate: &created}, date(2025, 4, 20), false},
+		{"31 en avril", db.RecurringOperation{DayOfMonth: 31, IsActive: true}, date(2025, 4, 30), true},
+	}
+	for _, tt := range tests {
+		if got := IsDue(tt.rec, tt.now); got != tt.want {
+			t.Errorf("%s: IsDue = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCalculateAllocations(t *testing.T) {
+	accounts := []db.Account{
+		{ID: 1, Name: "Courant", Balance: 1000},
+		{ID: 2, Name: "PEA", Balance: 0},
+		{ID: 3, Name: "Livret A", Balance: 0},
+	}
+	recurrings := []db.RecurringOperation{
+		{ID: 7, AccountID: 1, Amount: 3000, DayOfMonth: 28, IsActive: true},
+		{ID: 8, AccountID: 1, Amount: -900, DayOfMonth: 5, IsActive: true},
+	}
+	rules := []db.AllocationRule{
+		{RecurringID: 7, ToAccountID: 2, Percent: 20},
+		{RecurringID: 7, ToAccountID: 3, Percent: 10},
+	}
+
+	data := CalculateWithOptions(accounts, 1, Options{Recurrings: recurrings, Allocations: rules})
+	final := data.Projection[len(data.Projection)-1]
+	// 12 mois de 600 vers le PEA et 300 vers le livret, salaire non simule
+	if final.Accounts["PEA"] != 7200 || final.Accounts["Livret A"] != 3600 || final.Accounts["Courant"] != 1000 {
+		t.Errorf("final = %+v", final.Accounts)
+	}
+	if data.TotalInterests != 0 {
+		t.Errorf("TotalInterests = %v, want 0", data.TotalInterests)
+	}
+
+	ran := date(2025, 4, 28)
+	if !IsDue(recurrings[0], date(2025, 4, 30)) || IsDue(recurrings[0], date(2025, 4, 27)) {
+		t.Error("IsDue")
+	}
+	recurrings[0].LastRunDate = &ran
+	if IsDue(recurrings[0], date(2025, 4, 30)) || !IsDue(recurrings[0], date(2025, 5, 28)) {
+		t.Error("IsDue apres execution")
+	}
+}
+
+func TestPlanPayoffStrategies(t *testing.T) {
+	debts := []Debt{
+		{AccountID: 1, Name: "Auto", Balance: 6000, Rate: 4, MinPayment: 200},
+		{AccountID: 2, Name: "Revolving", Balance: 2000, Rate: 18, MinPayment: 60},
+		{AccountID: 3, Name: "Pret perso", Balance: 1000, Rate: 7, MinPayment: 50},
+	}
+	start := date(2025, 1, 15)
+
+	avalanche, err := PlanPayoff(debts, 150, StrategyAvalanche, nil, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snowball, err := PlanPayoff(debts, 150, StrategySnowball, nil, start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := avalanche.Order; got[0] != 2 || got[1] != 3 || got[2] != 1 {
+		t.Errorf("avalanche order = %v", got)
+	}
+	if got := snowball.Order; got[0] != 3 || got[1] != 2 {
+		t.Errorf("snowball order = %v", got)
+	}
+	if avalanche.TotalInterest >= snowball.TotalInterest {
+		t.Errorf("avalanche %v >= snowball %v", avalanche.TotalInterest, snowball.TotalInterest)
+	}
+	// Le petit pret est solde plus tot en boule de neige
+	if snowball.Debts[2].Months >= avalanche.Debts[2].Months {
+		t.Errorf("pret perso: snowball %d, avalanche %d", snowball.Debts[2].Months, avalanche.Debts[2].Months)
+	}
+	last := avalanche.Schedule[len(avalanche.Schedule)-1]
+	if last.TotalBalance != 0 || last.Date != avalanche.PayoffDate || avalanche.Months != len(avalanche.Schedule) {
+		t.Errorf("fin = %+v, plan %s/%d", last, avalanche.PayoffDate, avalanche.Months)
+	}
+	if math.Abs(avalanche.TotalPaid-9000-avalanche.TotalInterest) > 0.05 {
+		t.Errorf("paid = %v, interest = %v", avalanche.TotalPaid, avalanche.TotalInterest)
+	}
+
+	custom, _ := PlanPayoff(debts, 150, StrategyCustom, []int64{1}, start)
+	if got := custom.Order; got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Errorf("custom order = %v", got)
+	}
+
+	if _, err := PlanPayoff([]Debt{{AccountID: 1, Balance: 10000, Rate: 12, MinPayment: 50}}, 0, StrategyAvalanche, nil, start); err != ErrPayoffNotReached {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestValueAsset(t *testing.T) {
+	bought := date(2020, 1, 1)
+	house := db.Account{Kind: "ASSET", ValuationRule: "ANNUAL_RATE", ValuationRate: 2, PurchasePrice: 300000, PurchaseDate: &bought}
+	car := db.Account{Kind: "ASSET", ValuationRule: "LINEAR", DepreciationYears: 5, PurchasePrice: 24000, PurchaseDate: &bought}
+
+	tests := []struct {
+		name    string
+		acc     db.Account
+		balance float64
+		at      time.Time
+		want    float64
+	}{
+		{"appreciation", house, 300000, date(2021, 1, 1), 300000 * math.Pow(1.02, 1.0/12)},
+		{"amortissement", car, 12000, date(2022, 6, 1), 11600},
+		{"amortissement borne a zero", car, 150, date(2024, 6, 1), 0},
+		{"duree ecoulee", car, 500, date(2025, 1, 1), 500},
+		{"sans regle", db.Account{Kind: "ASSET"}, 1000, date(2025, 1, 1), 1000},
+	}
+	for _, tt := range tests {
+		if got := valueAsset(&tt.acc, tt.balance, tt.at); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%s: valueAsset = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	values := []struct {
+		acc  db.Account
+		at   time.Time
+		want float64
+		ok   bool
+	}{
+		{house, date(2022, 1, 1), 300000 * 1.02 * 1.02, true},
+		{car, date(2022, 7, 15), 24000 - 400*30, true},
+		{car, date(2019, 6, 1), 24000, true}, // Avant l'achat
+		{car, date(2030, 1, 1), 0, true},
+		{db.Account{Kind: "ASSET", ValuationRule: "LINEAR"}, date(2022, 1, 1), 0, false},
+	}
+	for _, tt := range values {
+		got, ok := AssetValueAt(tt.acc, tt.at)
+		if ok != tt.ok || math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("AssetValueAt(%s, %s) = %v, %v, want %v", tt.acc.ValuationRule, tt.at.Format("2006-01-02"), got, ok, tt.want)
+		}
+	}
+}
+
+func TestTermDeposits(t *testing.T) {
+	terms := []struct {
+		start, maturity   time.Time
+		wantStart, wantTo time.Time
+	}{
+		{date(2025, 1, 15), date(2025, 7, 15), date(2025, 7, 15), date(2026, 1, 15)},
+		{date(2025, 1, 31), date(2025, 4, 30), date(2025, 4, 30), date(2025, 7, 28)}, // 89 jours
+		{date(2025, 1, 1), date(2025, 1, 31), date(2025, 1, 31), date(2025, 3, 2)},   // 30 jours
+	}
+	for _, tt := range terms {
+		start, maturity := nextTerm(tt.start, tt.maturity)
+		if !start.Equal(tt.wantStart) || !maturity.Equal(tt.wantTo) {
+			t.Errorf("nextTerm(%s) = %s -> %s", tt.start.Format("2006-01-02"), start.Format("2006-01-02"), maturity.Format("2006-01-02"))
+		}
+	}
+
+	if got := termInterest(10000, 3, date(2025, 1, 1), date(2026, 1, 1)); math.Abs(got-300) > 1e-9 {
+		t.Errorf("termInterest 1 an = %v, want 300", got)
+	}
+	if got := termInterest(10000, 3, date(2025, 1, 1), date(2025, 7, 2)); math.Abs(got-10000*0.03*182/365) > 1e-9 {
+		t.Errorf("termInterest 182 jours = %v", got)
+	}
+
+	start, maturity := date(2024, 1, 1), date(2024, 7, 1)
+	acc := db.Account{Kind: "TERM_DEPOSIT", TermStartDate: &start, TermMaturityDate: &maturity, TermRollover: "ROLLOVER"}
+	if st := currentTerm(&acc, date(2025, 3, 1)); !st.active || !st.start.Equal(date(2025, 1, 1)) || !st.maturity.Equal(date(2025, 7, 1)) {
+		t.Errorf("currentTerm ROLLOVER = %+v", st)
+	}
+	acc.TermRollover = "TRANSFER"
+	if st := currentTerm(&acc, date(2025, 3, 1)); st.active {
+		t.Errorf("currentTerm TRANSFER = %+v", st)
+	}
+}
+
+func TestCalculateTermDepositWithYieldFlag(t *testing.T) {
+	// Un compte a terme dont le rendement est reste actif ne percoit que
+	// les interets du terme, a l'echeance
+	now := time.Now()
+	start, maturity := now.AddDate(0, -1, 0), now.AddDate(0, 5, 0)
+	target := int64(2)
+	accounts := []db.Account{
+		{ID: 1, Name: "CAT", Balance: 10000, Kind: "TERM_DEPOSIT", IsYieldActive: true, YieldMin: 5,
+			TermRate: 3, TermStartDate: &start, TermMaturityDate: &maturity, TermRollover: "TRANSFER", TermTargetAccountID: &target},
+		{ID: 2, Name: "Courant", Balance: 0},
+	}
+
+	data := Calculate(accounts, 1)
+	want := math.Round(termInterest(10000, 3, start, maturity))
+	if data.TotalInterests != want {
+		t.Errorf("TotalInterests = %v, want %v", data.TotalInterests, want)
+	}
+	final := data.Projection[len(data.Projection)-1]
+	if final.Accounts["CAT"] != 0 || final.Accounts["Courant"] != math.Round(10000+termInterest(10000, 3, start, maturity)) {
+		t.Errorf("final = %v", final.Accounts)
+	}
+}
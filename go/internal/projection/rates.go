@@ -0,0 +1,72 @@
+package projection
+
+import (
+	"sort"
+	"time"
+
+	"pilot-finance/internal/db"
+)
+
+// accountRate retourne le taux annuel effectif (%) d'un compte pour un solde et une date.
+// Le bareme de taux est prioritaire ; a defaut, le taux moyen YieldMin/YieldMax est utilise.
+func accountRate(acc *db.Account, balance float64, date time.Time) float64 {
+	if tiers := segmentsAt(acc.RateSchedule, date); len(tiers) > 0 {
+		return tieredRate(tiers, balance)
+	}
+
+	// Taux moyen
+	rate := acc.YieldMin
+	if acc.YieldType == "RANGE" {
+		rate = (acc.YieldMin + acc.YieldMax) / 2
+	}
+	return rate
+}
+
+// segmentsAt retourne les tranches en vigueur a une date (periode la plus recente
+// dont la date d'effet est passee), triees par seuil croissant
+func segmentsAt(schedule []db.RateSegment, date time.Time) []db.RateSegment {
+	var current time.Time
+	found := false
+	for _, seg := range schedule {
+		if seg.EffectiveDate.After(date) {
+			continue
+		}
+		if !found || seg.EffectiveDate.After(current) {
+			current = seg.EffectiveDate
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	var tiers []db.RateSegment
+	for _, seg := range schedule {
+		if seg.EffectiveDate.Equal(current) {
+			tiers = append(tiers, seg)
+		}
+	}
+	sort.Slice(tiers, func(i, j int) bool { return tiers[i].TierMin < tiers[j].TierMin })
+	return tiers
+}
+
+// tieredRate calcule le taux effectif d'un solde reparti sur des tranches marginales :
+// chaque tranche remunere la part du solde comprise entre son seuil et le seuil suivant
+func tieredRate(tiers []db.RateSegment, balance float64) float64 {
+	if balance <= 0 {
+		return tiers[0].Rate
+	}
+
+	var interest float64
+	for i, tier := range tiers {
+		if balance <= tier.TierMin {
+			break
+		}
+		upper := balance
+		if i+1 < len(tiers) && tiers[i+1].TierMin < balance {
+			upper = tiers[i+1].TierMin
+		}
+		interest += (upper - tier.TierMin) * tier.Rate / 100
+	}
+	return interest / balance * 100
+}
@@ -0,0 +1,107 @@
+package projection
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"pilot-finance/internal/db"
+)
+
+// termState suit le terme en cours d'un compte a terme pendant la simulation
+type termState struct {
+	start    time.Time
+	maturity time.Time
+	active   bool
+}
+
+// Maturity represente une echeance de compte a terme a venir
+type Maturity struct {
+	AccountID         int64   `json:"accountId"`
+	AccountName       string  `json:"accountName"`
+	Date              string  `json:"date"`
+	Principal         float64 `json:"principal"`
+	Interest          float64 `json:"interest"`
+	Amount            float64 `json:"amount"`
+	Rollover          string  `json:"rollover"`
+	TargetAccountName string  `json:"targetAccountName"`
+}
+
+// isTermDeposit indique si le compte est un compte a terme correctement parametre
+func isTermDeposit(acc *db.Account) bool {
+	return acc.Kind == "TERM_DEPOSIT" && acc.TermStartDate != nil && acc.TermMaturityDate != nil &&
+		acc.TermMaturityDate.After(*acc.TermStartDate)
+}
+
+// earnsYield indique si le compte est remunere chaque mois ; un compte a terme ne
+// percoit ses interets qu'a l'echeance, meme si son rendement est reste actif
+func earnsYield(acc *db.Account) bool {
+	return acc.IsYieldActive && acc.Kind != "TERM_DEPOSIT"
+}
+
+// nextTerm retourne le terme suivant un renouvellement, de meme duree
+func nextTerm(start, maturity time.Time) (time.Time, time.Time) {
+	if months := monthsBetween(start, maturity); months > 0 && start.AddDate(0, months, 0).Equal(maturity) {
+		return maturity, maturity.AddDate(0, months, 0)
+	}
+	return maturity, maturity.Add(maturity.Sub(start))
+}
+
+// termInterest calcule les interets simples d'un terme, verses a l'echeance
+func termInterest(principal, rate float64, start, maturity time.Time) float64 {
+	days := maturity.Sub(start).Hours() / 24
+	return principal * rate / 100 * days / 365
+}
+
+// currentTerm retourne le terme en cours a une date. Les termes deja echus sont
+// renouveles (ROLLOVER) ou consideres comme clotures (TRANSFER).
+func currentTerm(acc *db.Account, now time.Time) termState {
+	st := termState{start: *acc.TermStartDate, maturity: *acc.TermMaturityDate, active: true}
+	for !st.maturity.After(now) {
+		if acc.TermRollover != "ROLLOVER" {
+			st.active = false
+			break
+		}
+		st.start, st.maturity = nextTerm(st.start, st.maturity)
+	}
+	return st
+}
+
+// UpcomingMaturities liste les echeances des comptes a terme dans les N prochains mois
+func UpcomingMaturities(accounts []db.Account, now time.Time, months int) []Maturity {
+	nameByID := make(map[int64]string)
+	for _, acc := range accounts {
+		nameByID[acc.ID] = acc.Name
+	}
+
+	limit := now.AddDate(0, months, 0)
+	maturities := []Maturity{}
+	for i := range accounts {
+		acc := &accounts[i]
+		if !isTermDeposit(acc) {
+			continue
+		}
+		st := currentTerm(acc, now)
+		if !st.active || st.maturity.After(limit) {
+			continue
+		}
+
+		interest := termInterest(acc.Balance, acc.TermRate, st.start, st.maturity)
+		m := Maturity{
+			AccountID:   acc.ID,
+			AccountName: acc.Name,
+			Date:        st.maturity.Format("2006-01-02"),
+			Principal:   acc.Balance,
+			Interest:    math.Round(interest*100) / 100,
+			Amount:      math.Round((acc.Balance+interest)*100) / 100,
+			Rollover:    acc.TermRollover,
+		}
+		if acc.TermRollover == "TRANSFER" && acc.TermTargetAccountID != nil {
+			m.TargetAccountName = nameByID[*acc.TermTargetAccountID]
+		}
+		maturities = append(maturities, m)
+	}
+
+	sort.Slice(maturities, func(i, j int) bool { return maturities[i].Date < maturities[j].Date })
+	return maturities
+}
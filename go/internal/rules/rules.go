@@ -0,0 +1,128 @@
+// Package rules applique les regles de categorisation automatique des transactions.
+package rules
+
+import (
+	"errors"
+	"strings"
+
+	"pilot-finance/internal/textnorm"
+)
+
+// Rule associe des conditions (toutes requises) a des actions.
+// Elle est stockee chiffree en JSON, sans son ID.
+type Rule struct {
+	ID       int64  `json:"id,omitempty"`
+	Name     string `json:"name"`
+	IsActive bool   `json:"isActive"`
+
+	// Conditions
+	DescriptionContains string   `json:"descriptionContains,omitempty"` // Comparaison sans casse ni accents
+	AmountMin           *float64 `json:"amountMin,omitempty"`
+	AmountMax           *float64 `json:"amountMax,omitempty"`
+	AccountID           *int64   `json:"accountId,omitempty"`
+
+	// Actions
+	Category string   `json:"category,omitempty"`
+	Payee    string   `json:"payee,omitempty"` // Renomme le tiers
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// Validate verifie qu'une regle a au moins une condition et une action
+func (r Rule) Validate() error {
+	if strings.TrimSpace(r.DescriptionContains) == "" && r.AmountMin == nil && r.AmountMax == nil && r.AccountID == nil {
+		return errors.New("au moins une condition requise")
+	}
+	if r.Category == "" && r.Payee == "" && len(r.Tags) == 0 {
+		return errors.New("au moins une action requise")
+	}
+	if r.AmountMin != nil && r.AmountMax != nil && *r.AmountMin > *r.AmountMax {
+		return errors.New("montant minimum superieur au maximum")
+	}
+	return nil
+}
+
+// Target est une transaction dechiffree soumise aux regles
+type Target struct {
+	AccountID   int64
+	Amount      float64
+	Description string
+	Category    string
+	Payee       string
+	Tags        []string
+}
+
+// Matches indique si la transaction remplit toutes les conditions de la regle
+func (r Rule) Matches(t Target) bool {
+	if !r.IsActive {
+		return false
+	}
+	if r.AccountID != nil && *r.AccountID != t.AccountID {
+		return false
+	}
+	if r.AmountMin != nil && t.Amount < *r.AmountMin {
+		return false
+	}
+	if r.AmountMax != nil && t.Amount > *r.AmountMax {
+		return false
+	}
+	if pattern := textnorm.Normalize(r.DescriptionContains); pattern != "" {
+		haystack := textnorm.Normalize(t.Description + " " + t.Payee)
+		if !strings.Contains(haystack, pattern) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply applique la premiere regle correspondante. Sans overwrite, la categorie
+// et le tiers deja renseignes sont conserves. Retourne true si la cible a change.
+func Apply(rules []Rule, t *Target, overwrite bool) bool {
+	for _, r := range rules {
+		if !r.Matches(*t) {
+			continue
+		}
+
+		changed := false
+		if r.Category != "" && r.Category != t.Category && (t.Category == "" || overwrite) {
+			t.Category = r.Category
+			changed = true
+		}
+		if r.Payee != "" && r.Payee != t.Payee && (t.Payee == "" || overwrite) {
+			t.Payee = r.Payee
+			changed = true
+		}
+		for _, tag := range r.Tags {
+			if !hasTag(t.Tags, tag) {
+				t.Tags = append(t.Tags, tag)
+				changed = true
+			}
+		}
+		return changed
+	}
+	return false
+}
+
+// SplitTags decoupe une liste d'etiquettes separees par des virgules
+func SplitTags(s string) []string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" && !hasTag(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// JoinTags assemble des etiquettes pour le stockage
+func JoinTags(tags []string) string {
+	return strings.Join(tags, ",")
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
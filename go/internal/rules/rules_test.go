@@ -0,0 +1,39 @@
+package rules
+
+import "testing"
+
+func TestApplyFirstMatchingRule(t *testing.T) {
+	min, max := -100.0, 0.0
+	account := int64(2)
+	rules := []Rule{
+		{Name: "inactive", IsActive: false, DescriptionContains: "carrefour", Category: "Ignored"},
+		{Name: "courses", IsActive: true, DescriptionContains: "Carrefour Market", AmountMin: &min, AmountMax: &max,
+			Category: "Courses", Payee: "Carrefour", Tags: []string{"alimentation"}},
+		{Name: "compte", IsActive: true, AccountID: &account, Category: "Epargne"},
+	}
+
+	target := Target{AccountID: 1, Amount: -42.3, Description: "CB CARREFOUR  MARKET 03/01"}
+	if !Apply(rules, &target, false) {
+		t.Fatal("expected a change")
+	}
+	if target.Category != "Courses" || target.Payee != "Carrefour" || len(target.Tags) != 1 {
+		t.Errorf("target = %+v", target)
+	}
+
+	// Montant hors bornes : la regle suivante ne concerne pas ce compte
+	big := Target{AccountID: 1, Amount: -250, Description: "CARREFOUR MARKET"}
+	if Apply(rules, &big, false) {
+		t.Errorf("unexpected change: %+v", big)
+	}
+
+	// Categorie deja saisie conservee sans overwrite
+	kept := Target{AccountID: 2, Amount: 10, Category: "Cadeau"}
+	Apply(rules, &kept, false)
+	if kept.Category != "Cadeau" {
+		t.Errorf("category overwritten: %+v", kept)
+	}
+	Apply(rules, &kept, true)
+	if kept.Category != "Epargne" {
+		t.Errorf("overwrite ignored: %+v", kept)
+	}
+}
@@ -0,0 +1,300 @@
+// Package subscriptions detecte les abonnements dans l'historique des transactions :
+// des prelevements du meme beneficiaire, d'un montant proche, chaque mois ou chaque annee
+package subscriptions
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"pilot-finance/internal/db"
+	"pilot-finance/internal/ledger"
+	"pilot-finance/internal/textnorm"
+)
+
+// Frequences de prelevement
+const (
+	CadenceMonthly = "MONTHLY"
+	CadenceYearly  = "YEARLY"
+)
+
+// cadence decrit l'ecart attendu entre deux prelevements, en jours
+type cadence struct {
+	name           string
+	minDays        int
+	maxDays        int
+	minOccurrences int // Prelevements necessaires pour conclure
+	graceDays      int // Retard tolere avant de considerer l'abonnement arrete
+	perYear        float64
+}
+
+var cadences = []cadence{
+	{name: CadenceMonthly, minDays: 26, maxDays: 35, minOccurrences: 3, graceDays: 10, perYear: 12},
+	{name: CadenceYearly, minDays: 350, maxDays: 380, minOccurrences: 2, graceDays: 31, perYear: 1},
+}
+
+// amountTolerance est l'ecart relatif admis entre deux prelevements successifs,
+// assez large pour suivre une hausse de prix
+const amountTolerance = 0.3
+
+// closeAmountTolerance est l'ecart relatif admis pour rattacher un abonnement a une
+// operation recurrente d'un autre libelle, prelevee a quelques jours pres
+const closeAmountTolerance = 0.05
+
+// PriceChange est la derniere evolution de prix d'un abonnement
+type PriceChange struct {
+	Previous float64 `json:"previous"`
+	Current  float64 `json:"current"`
+	Date     string  `json:"date"`
+}
+
+// Subscription est un abonnement detecte
+type Subscription struct {
+	Name          string       `json:"name"`
+	AccountID     int64        `json:"accountId"`
+	Category      *string      `json:"category"`
+	Cadence       string       `json:"cadence"`
+	Amount        float64      `json:"amount"` // Dernier prelevement (positif)
+	AnnualCost    float64      `json:"annualCost"`
+	Occurrences   int          `json:"occurrences"`
+	FirstDate     string       `json:"firstDate"`
+	LastDate      string       `json:"lastDate"`
+	NextDate      string       `json:"nextDate"`
+	Stopped       bool         `json:"stopped"` // Prochain prelevement en retard
+	PriceIncrease *PriceChange `json:"priceIncrease,omitempty"`
+	// RecurringID est l'operation recurrente qui suit deja l'abonnement
+	RecurringID *int64 `json:"recurringId,omitempty"`
+	// Proposal est l'operation recurrente a creer pour un abonnement mensuel non suivi
+	Proposal *db.RecurringOperation `json:"proposal,omitempty"`
+}
+
+// Report liste les abonnements detectes, actifs d'abord
+type Report struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+	MonthlyCost   float64        `json:"monthlyCost"` // Abonnements actifs
+	AnnualCost    float64        `json:"annualCost"`
+}
+
+// key regroupe les prelevements d'un meme beneficiaire : le beneficiaire s'il est
+// renseigne, sinon le libelle, sans les mots contenant des chiffres (dates, references)
+func key(t db.Transaction) string {
+	text := t.Description
+	if t.Payee != nil && strings.TrimSpace(*t.Payee) != "" {
+		text = *t.Payee
+	}
+	var words []string
+	for _, w := range textnorm.Tokens(text) {
+		if !strings.ContainsAny(w, "0123456789") {
+			words = append(words, w)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+func name(t db.Transaction) string {
+	if t.Payee != nil && strings.TrimSpace(*t.Payee) != "" {
+		return *t.Payee
+	}
+	return t.Description
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
+
+// similar indique deux montants (positifs) assez proches pour un meme abonnement
+func similar(a, b float64) bool {
+	return math.Abs(a-b) <= amountTolerance*math.Max(a, b)
+}
+
+// Detect recherche les abonnements dans les depenses (descriptions et beneficiaires
+// dechiffres), hors virements. Pour chaque beneficiaire d'un compte, la chaine de
+// prelevements reguliers est remontee depuis le plus recent ; un achat ponctuel du
+// meme beneficiaire entre deux prelevements est ignore, un ecart trop long ou un
+// montant trop different l'interrompt. Les operations recurrentes (descriptions
+// dechiffrees) servent a reconnaitre les abonnements deja suivis.
+func Detect(txs []db.Transaction, recurrings []db.RecurringOperation, now time.Time) Report {
+	type group struct {
+		accountID int64
+		key       string
+	}
+	charges := make(map[group][]db.Transaction)
+	var order []group
+	for _, t := range txs {
+		if t.Amount >= 0 || ledger.IsTransfer(t) {
+			continue
+		}
+		g := group{t.AccountID, key(t)}
+		if g.key == "" {
+			continue
+		}
+		if _, ok := charges[g]; !ok {
+			order = append(order, g)
+		}
+		charges[g] = append(charges[g], t)
+	}
+
+	report := Report{Subscriptions: []Subscription{}}
+	var keys []string
+	var lasts []db.Transaction
+	for _, g := range order {
+		list := charges[g]
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Date.Before(list[j].Date) })
+
+		sub, ok := detectChain(list, now)
+		if !ok {
+			continue
+		}
+		report.Subscriptions = append(report.Subscriptions, sub)
+		keys = append(keys, g.key)
+		lasts = append(lasts, list[len(list)-1])
+		if !sub.Stopped {
+			report.AnnualCost += sub.AnnualCost
+		}
+	}
+	matchRecurring(report.Subscriptions, keys, lasts, recurrings)
+
+	sort.SliceStable(report.Subscriptions, func(i, j int) bool {
+		a, b := report.Subscriptions[i], report.Subscriptions[j]
+		if a.Stopped != b.Stopped {
+			return !a.Stopped
+		}
+		return a.AnnualCost > b.AnnualCost
+	})
+	report.MonthlyCost = round(report.AnnualCost / 12)
+	report.AnnualCost = round(report.AnnualCost)
+	return report
+}
+
+// detectChain remonte les prelevements reguliers depuis le plus recent, pour la
+// premiere frequence qui reunit assez d'occurrences
+func detectChain(list []db.Transaction, now time.Time) (Subscription, bool) {
+	last := list[len(list)-1]
+	for _, c := range cadences {
+		chain := []db.Transaction{last}
+		for i := len(list) - 2; i >= 0; i-- {
+			prev, next := list[i], chain[len(chain)-1]
+			days := daysBetween(prev.Date, next.Date)
+			if days < c.minDays {
+				continue // Doublon ou achat intercale : ignore
+			}
+			if days > c.maxDays || !similar(-prev.Amount, -next.Amount) {
+				break
+			}
+			chain = append(chain, prev)
+		}
+		if len(chain) < c.minOccurrences {
+			continue
+		}
+
+		amount := -last.Amount
+		next := last.Date.AddDate(0, 1, 0)
+		if c.name == CadenceYearly {
+			next = last.Date.AddDate(1, 0, 0)
+		}
+		sub := Subscription{
+			Name:        name(last),
+			AccountID:   last.AccountID,
+			Category:    last.Category,
+			Cadence:     c.name,
+			Amount:      round(amount),
+			AnnualCost:  round(amount * c.perYear),
+			Occurrences: len(chain),
+			FirstDate:   chain[len(chain)-1].Date.Format("2006-01-02"),
+			LastDate:    last.Date.Format("2006-01-02"),
+			NextDate:    next.Format("2006-01-02"),
+			Stopped:     now.After(next.AddDate(0, 0, c.graceDays)),
+		}
+		// Derniere evolution de prix, signalee si c'est une hausse
+		for i := 0; i+1 < len(chain); i++ {
+			current, previous := -chain[i].Amount, -chain[i+1].Amount
+			if math.Abs(current-previous) < 0.005 {
+				continue
+			}
+			if current > previous {
+				sub.PriceIncrease = &PriceChange{
+					Previous: round(previous),
+					Current:  round(current),
+					Date:     chain[i].Date.Format("2006-01-02"),
+				}
+			}
+			break
+		}
+		return sub, true
+	}
+	return Subscription{}, false
+}
+
+// closeDay indique une operation recurrente prelevee a 3 jours pres du dernier
+// prelevement, d'un mois sur l'autre compris (le 30 et le 1er)
+func closeDay(rec db.RecurringOperation, last db.Transaction) bool {
+	length := time.Date(last.Date.Year(), last.Date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	recDay := rec.DayOfMonth
+	if recDay > length {
+		recDay = length
+	}
+	gap := recDay - last.Date.Day()
+	if gap < 0 {
+		gap = -gap
+	}
+	return gap <= 3 || length-gap <= 3
+}
+
+// matchRecurring rattache chaque abonnement a une depense recurrente active du meme
+// compte : de meme libelle et de montant proche, a defaut prelevee a quelques jours
+// pres pour un montant quasi identique. Une operation ne suit qu'un abonnement. Un
+// abonnement mensuel actif sans operation recurrente donne lieu a une proposition.
+func matchRecurring(subs []Subscription, keys []string, lasts []db.Transaction, recurrings []db.RecurringOperation) {
+	claimed := make(map[int64]bool)
+	link := func(i int, match func(rec db.RecurringOperation) bool) {
+		sub := &subs[i]
+		if sub.RecurringID != nil {
+			return
+		}
+		for _, rec := range recurrings {
+			if claimed[rec.ID] || !rec.IsActive || rec.AccountID != sub.AccountID ||
+				rec.ToAccountID != nil || rec.Amount >= 0 || !match(rec) {
+				continue
+			}
+			id := rec.ID
+			sub.RecurringID = &id
+			claimed[id] = true
+			return
+		}
+	}
+
+	// Le libelle d'abord, pour qu'un rapprochement par date ne prenne pas
+	// l'operation d'un autre abonnement
+	for i := range subs {
+		link(i, func(rec db.RecurringOperation) bool {
+			return similar(-rec.Amount, subs[i].Amount) &&
+				strings.Contains(textnorm.Normalize(rec.Description), keys[i])
+		})
+	}
+	for i := range subs {
+		link(i, func(rec db.RecurringOperation) bool {
+			return math.Abs(-rec.Amount-subs[i].Amount) <= closeAmountTolerance*subs[i].Amount &&
+				closeDay(rec, lasts[i])
+		})
+	}
+
+	for i := range subs {
+		sub := &subs[i]
+		if sub.RecurringID != nil || sub.Cadence != CadenceMonthly || sub.Stopped {
+			continue
+		}
+		sub.Proposal = &db.RecurringOperation{
+			AccountID:   sub.AccountID,
+			Amount:      -sub.Amount,
+			Description: sub.Name,
+			DayOfMonth:  lasts[i].Date.Day(),
+			IsActive:    true,
+			Category:    sub.Category,
+		}
+	}
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
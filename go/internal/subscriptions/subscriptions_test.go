@@ -0,0 +1,111 @@
+package subscriptions
+
+import (
+	"testing"
+	"time"
+
+	"pilot-finance/internal/db"
+)
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestDetect(t *testing.T) {
+	netflix := "Netflix"
+	linked := int64(99)
+	txs := []db.Transaction{
+		// Mensuel avec hausse de prix en mars
+		{AccountID: 1, Amount: -13.49, Description: "PRLV NETFLIX 0112", Payee: &netflix, Date: day(2025, 1, 12)},
+		{AccountID: 1, Amount: -13.49, Description: "PRLV NETFLIX 0212", Payee: &netflix, Date: day(2025, 2, 12)},
+		{AccountID: 1, Amount: -15.99, Description: "PRLV NETFLIX 0312", Payee: &netflix, Date: day(2025, 3, 12)},
+		{AccountID: 1, Amount: -15.99, Description: "PRLV NETFLIX 0412", Payee: &netflix, Date: day(2025, 4, 12)},
+		// Annuel
+		{AccountID: 1, Amount: -49, Description: "Amazon Prime 2023", Date: day(2023, 5, 2)},
+		{AccountID: 1, Amount: -69.9, Description: "Amazon Prime 2024", Date: day(2024, 5, 3)},
+		// Mensuel arrete en janvier, deja suivi par une operation recurrente
+		{AccountID: 1, Amount: -30, Description: "Salle de sport", Date: day(2024, 11, 5)},
+		{AccountID: 1, Amount: -30, Description: "Salle de sport", Date: day(2024, 12, 5)},
+		{AccountID: 1, Amount: -30, Description: "Salle de sport", Date: day(2025, 1, 5)},
+		// Achats irreguliers et virements ignores
+		{AccountID: 1, Amount: -12, Description: "Boulangerie", Date: day(2025, 2, 1)},
+		{AccountID: 1, Amount: -85, Description: "Boulangerie", Date: day(2025, 3, 1)},
+		{AccountID: 1, Amount: -12, Description: "Boulangerie", Date: day(2025, 4, 1)},
+		{AccountID: 1, Amount: -100, Description: "Epargne", Date: day(2025, 2, 1), LinkedID: &linked},
+		{AccountID: 1, Amount: -100, Description: "Epargne", Date: day(2025, 3, 1), LinkedID: &linked},
+		{AccountID: 1, Amount: -100, Description: "Epargne", Date: day(2025, 4, 1), LinkedID: &linked},
+	}
+	recurrings := []db.RecurringOperation{
+		{ID: 7, AccountID: 1, Amount: -30, Description: "Salle de sport", DayOfMonth: 5, IsActive: true},
+	}
+
+	report := Detect(txs, recurrings, day(2025, 4, 20))
+	if len(report.Subscriptions) != 3 {
+		t.Fatalf("subscriptions = %+v", report.Subscriptions)
+	}
+
+	n := report.Subscriptions[0]
+	if n.Name != "Netflix" || n.Cadence != CadenceMonthly || n.Occurrences != 4 || n.Stopped || n.AnnualCost != 191.88 {
+		t.Errorf("netflix = %+v", n)
+	}
+	if n.PriceIncrease == nil || n.PriceIncrease.Previous != 13.49 || n.PriceIncrease.Date != "2025-03-12" {
+		t.Errorf("hausse = %+v", n.PriceIncrease)
+	}
+	if n.Proposal == nil || n.Proposal.Amount != -15.99 || n.Proposal.DayOfMonth != 12 {
+		t.Errorf("proposition = %+v", n.Proposal)
+	}
+
+	a := report.Subscriptions[1]
+	if a.Cadence != CadenceYearly || a.Stopped || a.AnnualCost != 69.9 || a.Proposal != nil || a.NextDate != "2025-05-03" {
+		t.Errorf("annuel = %+v", a)
+	}
+
+	s := report.Subscriptions[2]
+	if !s.Stopped || s.RecurringID == nil || *s.RecurringID != 7 || s.Proposal != nil {
+		t.Errorf("arrete = %+v", s)
+	}
+	if report.AnnualCost != 261.78 || report.MonthlyCost != 21.82 {
+		t.Errorf("totaux = %v / %v", report.AnnualCost, report.MonthlyCost)
+	}
+}
+
+func TestMatchRecurring(t *testing.T) {
+	monthly := func(description string, amount float64, d int) []db.Transaction {
+		var txs []db.Transaction
+		for m := time.February; m <= time.April; m++ {
+			txs = append(txs, db.Transaction{AccountID: 1, Amount: amount, Description: description, Date: day(2025, m, d)})
+		}
+		return txs
+	}
+	var txs []db.Transaction
+	txs = append(txs, monthly("Spotify", -10.99, 12)...)
+	txs = append(txs, monthly("Disney Plus", -10.99, 13)...)
+	txs = append(txs, monthly("Mutuelle", -42, 1)...)
+	txs = append(txs, monthly("Canal", -25, 20)...)
+	recurrings := []db.RecurringOperation{
+		// Meme prix et jour proche que Disney, mais rattachee a Spotify par son libelle
+		{ID: 1, AccountID: 1, Amount: -10.99, Description: "Spotify", DayOfMonth: 12, IsActive: true},
+		// Prelevee le 30 pour un abonnement debite le 1er
+		{ID: 2, AccountID: 1, Amount: -42, Description: "Assurance sante", DayOfMonth: 30, IsActive: true},
+		// Inactive : ignoree
+		{ID: 3, AccountID: 1, Amount: -25, Description: "Canal", DayOfMonth: 20},
+	}
+
+	report := Detect(txs, recurrings, day(2025, 4, 25))
+	byName := make(map[string]Subscription)
+	for _, s := range report.Subscriptions {
+		byName[s.Name] = s
+	}
+	if s := byName["Spotify"]; s.RecurringID == nil || *s.RecurringID != 1 {
+		t.Errorf("spotify = %+v", s)
+	}
+	if s := byName["Disney Plus"]; s.RecurringID != nil || s.Proposal == nil {
+		t.Errorf("disney = %+v", s)
+	}
+	if s := byName["Mutuelle"]; s.RecurringID == nil || *s.RecurringID != 2 {
+		t.Errorf("mutuelle = %+v", s)
+	}
+	if s := byName["Canal"]; s.RecurringID != nil || s.Proposal == nil {
+		t.Errorf("canal = %+v", s)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"pilot-finance/internal/ledger"
 )
 
 // pageTemplates stocke un template combiné (base + components + page) pour chaque page
@@ -29,6 +31,7 @@ var FuncMap = template.FuncMap{
 	"eq":                 eqFunc,
 	"ne":                 neFunc,
 	"abs":                absFunc,
+	"unallocated":        ledger.Unallocated,
 }
 
 // Init charge tous les templates depuis le dossier templates
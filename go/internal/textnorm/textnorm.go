@@ -0,0 +1,50 @@
+// Package textnorm normalise les libelles pour les comparer malgre les
+// differences de casse, d'accents et de ponctuation entre banques.
+package textnorm
+
+import (
+	"strings"
+	"unicode"
+)
+
+// folds remplace les lettres accentuees courantes par leur forme de base
+var folds = map[rune]string{
+	'à': "a", 'â': "a", 'ä': "a", 'á': "a", 'ã': "a", 'å': "a",
+	'ç': "c",
+	'é': "e", 'è': "e", 'ê': "e", 'ë': "e",
+	'î': "i", 'ï': "i", 'í': "i", 'ì': "i",
+	'ô': "o", 'ö': "o", 'ó': "o", 'ò': "o", 'õ': "o",
+	'ù': "u", 'û': "u", 'ü': "u", 'ú': "u",
+	'ÿ': "y", 'ñ': "n",
+	'œ': "oe", 'æ': "ae", 'ß': "ss",
+}
+
+// Normalize met en minuscules, retire les accents et remplace la ponctuation
+// par des espaces simples : "CB Café  - Paris" devient "cb cafe paris"
+func Normalize(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	space := false
+	for _, r := range strings.ToLower(s) {
+		if f, ok := folds[r]; ok {
+			b.WriteString(f)
+			space = false
+			continue
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			space = false
+			continue
+		}
+		if !space && b.Len() > 0 {
+			b.WriteByte(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Tokens decoupe un texte normalise en mots
+func Tokens(s string) []string {
+	return strings.Fields(Normalize(s))
+}
@@ -0,0 +1,17 @@
+package textnorm
+
+import "testing"
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"CB Café  - Paris":        "cb cafe paris",
+		"PRLV SEPA Électricité'":  "prlv sepa electricite",
+		"  Cœur de Bœuf, 12/03  ": "coeur de boeuf 12 03",
+		"":                        "",
+	}
+	for in, want := range cases {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}